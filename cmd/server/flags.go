@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// VarFlags are the name=value pairs set with the -var flag
+type VarFlags [][2]string
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (v *VarFlags) String() string {
+	str := make([]string, 0, len(*v))
+	for _, pair := range *v {
+		str = append(str, pair[0]+"="+pair[1])
+	}
+	return strings.Join(str, " ")
+}
+
+func (v *VarFlags) Set(value string) error {
+	if name, value, ok := strings.Cut(value, "="); !ok || name == "" {
+		return ErrBadParameter.With("expected name=value")
+	} else {
+		*v = append(*v, [2]string{name, value})
+	}
+	return nil
+}
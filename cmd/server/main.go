@@ -3,7 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -11,28 +13,41 @@ import (
 
 	// Modules
 	multierror "github.com/hashicorp/go-multierror"
+	hcl2 "github.com/hashicorp/hcl/v2"
 	config "github.com/mutablelogic/terraform-provider-nginx/pkg/config"
 	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	hcl "github.com/mutablelogic/terraform-provider-nginx/pkg/hcl"
 	plugin "github.com/mutablelogic/terraform-provider-nginx/pkg/plugin"
 	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/provider-gateway"
 
 	// Namespace imports
 	. "github.com/djthorpe/go-errors"
-	//. "github.com/mutablelogic/terraform-provider-nginx"
-	//. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
 )
 
 var (
 	flagAddr    = flag.String("addr", "", "Address to listen on")
 	flagPlugins = flag.String("plugins", "", "Plugin folder")
+	flagVars    = VarFlags{}
+	flagRestart = flag.String("restart", "never", "Restart policy for tasks (never, on-failure, always)")
+	flagMax     = flag.Uint("max-restarts", 0, "Maximum number of restarts for each task, or zero for no limit")
 )
 
+// Decoder parses HCL files and folders into task configurations
+type Decoder interface {
+	Parse(fs.FS, ...string) ([]TaskPlugin, error)
+}
+
 const (
 	defaultPluginPattern = "*.plugin"
+	fileExtHCL           = ".hcl"
 	pathSeparator        = string(os.PathSeparator)
 )
 
 func main() {
+	flag.Var(&flagVars, "var", "Set a variable value (name=value), can be repeated")
 	flag.Parse()
 
 	// PluginPath defaults to same folder as executable
@@ -49,6 +64,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Add built-in plugins
+	if _, exists := plugins[gateway.DefaultName]; !exists {
+		plugins[gateway.DefaultName] = gateway.Config{}
+	}
+
 	// Create context with the address
 	ctx := context.ContextForSignal(os.Interrupt, syscall.SIGTERM)
 	if *flagAddr != "" {
@@ -62,19 +82,141 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Get the resources from JSON files
-	var result error
+	// Register plugins and set variables with the HCL decoder
+	decoder := hcl.NewDecoder()
+	for _, plugin := range plugins {
+		if err := decoder.Register(plugin); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	for _, v := range flagVars {
+		if err := decoder.SetVar(v[0], v[1]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
+	// Create a provider with a supervision policy
+	restart, err := provider.ParseRestart(*flagRestart)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	policy := provider.Policy{Restart: restart, MaxRestarts: *flagMax}
 	provider := provider.New()
+	provider.Policy = policy
+
+	// Get the resources from HCL and JSON files
 	fs := os.DirFS(string(os.PathSeparator))
-	for _, arg := range flag.Args() {
+	configs, err := ReadConfigs(fs, wd, plugins, decoder, flag.Args()...)
+	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Order the configurations and instantiate the plugins in dependency order
+	configs, err = provider.Order(configs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var diags hcl2.Diagnostics
+	for _, plugin := range configs {
+		if task, err := provider.New(ctx, plugin); err != nil {
+			diags = append(diags, decoder.NewDiagnostic(plugin, err))
+			break
+		} else {
+			fmt.Printf("task=%v\n", task)
+		}
+	}
+	if diags.HasErrors() {
+		decoder.WriteDiagnostics(os.Stderr, diags)
+		os.Exit(1)
+	}
+
+	// Reload the configuration files on SIGHUP, replacing only the tasks
+	// whose configuration changed
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				signal.Stop(hup)
+				return
+			case <-hup:
+				if configs, err := ReadConfigs(fs, wd, plugins, decoder, flag.Args()...); err != nil {
+					decoder.WriteDiagnostics(os.Stderr, err)
+				} else if changes, err := provider.Reload(ctx, configs...); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				} else {
+					fmt.Printf("reload=%v\n", changes)
+				}
+			}
+		}
+	}()
+
+	// Subscribe to events from the provider, where events for each request
+	// are not printed
+	var wg sync.WaitGroup
+	var retVal = 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for evt := range provider.Sub() {
+			if !IsRequestEvent(evt) {
+				fmt.Printf("event=%v\n", evt)
+			}
+		}
+	}()
+
+	// Run the provider until done
+	fmt.Println("Press CTRL+C to exit")
+	if err := provider.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		retVal = -1
+	}
+
+	// Wait for end of events
+	wg.Wait()
+	fmt.Println("Done")
+	os.Exit(retVal)
+}
+
+// IsRequestEvent returns true for events which are emitted for each request,
+// such as a request served by a router or a token matched by token
+// authentication
+func IsRequestEvent(evt Event) bool {
+	switch evt.Key() {
+	case RouterRequest, TokenMatch, TokenMiss:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadConfigs returns the task configurations from JSON and HCL files and
+// folders, where HCL files and folders are decoded together. Relative paths
+// are resolved against the working directory
+func ReadConfigs(filesys fs.FS, wd string, plugins map[string]TaskPlugin, decoder Decoder, args ...string) ([]TaskPlugin, error) {
+	var result error
+	var configs []TaskPlugin
+	var hclpaths []string
+	for _, arg := range args {
 		// Make absolute path
 		if !filepath.IsAbs(arg) {
 			arg = filepath.Join(wd, arg)
 		}
 
+		// HCL files and folders are decoded together
+		if IsHCLPath(arg) {
+			hclpaths = append(hclpaths, arg)
+			continue
+		}
+
 		// Parse JSON files
-		resources, err := config.LoadJSONForPattern(fs, strings.TrimPrefix(arg, pathSeparator))
+		resources, err := config.LoadJSONForPattern(filesys, strings.TrimPrefix(arg, pathSeparator))
 		if err != nil {
 			result = multierror.Append(result, err)
 			continue
@@ -89,50 +231,31 @@ func main() {
 			}
 
 			// Create a task plugin from the JSON
-			plugin, err := config.ParseJSONResource(fs, resource, plugin)
+			plugin, err := config.ParseJSONResource(filesys, resource, plugin)
 			if err != nil {
 				result = multierror.Append(result, err)
 				continue
 			}
 
-			// Instantiate the plugin into a task
-			task, err := provider.New(ctx, plugin)
-			if err != nil {
-				result = multierror.Append(result, err)
-				continue
-			}
-
-			fmt.Printf("task=%v\n", task)
+			// Append the configuration
+			configs = append(configs, plugin)
 		}
 	}
-
 	if result != nil {
-		fmt.Fprintln(os.Stderr, result)
-		os.Exit(1)
+		return nil, result
 	}
 
-	// Subscribe to events from the provider
-	var wg sync.WaitGroup
-	var retVal = 0
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		for evt := range provider.Sub() {
-			fmt.Printf("event=%v\n", evt)
+	// Decode HCL
+	if len(hclpaths) > 0 {
+		plugins, err := decoder.Parse(filesys, hclpaths...)
+		if err != nil {
+			return nil, err
 		}
-	}()
-
-	// Run the provider until done
-	fmt.Println("Press CTRL+C to exit")
-	if err := provider.Run(ctx); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		retVal = -1
+		configs = append(configs, plugins...)
 	}
 
-	// Wait for end of events
-	wg.Wait()
-	fmt.Println("Done")
-	os.Exit(retVal)
+	// Return success
+	return configs, nil
 }
 
 func GetPluginPath(defaultPath string) (string, error) {
@@ -151,3 +274,14 @@ func GetPluginPath(defaultPath string) (string, error) {
 	}
 	return defaultPath, nil
 }
+
+// IsHCLPath returns true if the path is a folder or a HCL file
+func IsHCLPath(path string) bool {
+	if strings.ToLower(filepath.Ext(path)) == fileExtHCL {
+		return true
+	} else if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return true
+	} else {
+		return false
+	}
+}
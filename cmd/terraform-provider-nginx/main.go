@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	// Modules
+	providerserver "github.com/hashicorp/terraform-plugin-framework/providerserver"
+	terraform "github.com/mutablelogic/terraform-provider-nginx/pkg/terraform"
+)
+
+var (
+	flagDebug = flag.Bool("debug", false, "Run the provider with support for debuggers")
+)
+
+const (
+	providerAddress = "registry.terraform.io/mutablelogic/nginx"
+)
+
+var (
+	// Set by the linker
+	version = "dev"
+)
+
+func main() {
+	flag.Parse()
+
+	// Serve the provider until terraform terminates it
+	if err := providerserver.Serve(context.Background(), terraform.New(version), providerserver.ServeOpts{
+		Address: providerAddress,
+		Debug:   *flagDebug,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
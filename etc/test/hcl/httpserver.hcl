@@ -9,13 +9,24 @@ httpserver "server" {
     listen = var.listener
 }
 
-tokenauth-gateway "tokenauth" {
+tokenauth "main" {
+    path = "/var/lib/nginx-gateway"
+    delta = "30s"
+}
+
+tokenauth-gw "tokenauth" {
     router = httpserver.server
+    auth = tokenauth.main
     prefix = "/api/tokenauth/v1"
 }
 
-nginx-gateway "nginx" {
+nginx "main" {
+    conf_path = "/etc/nginx"
+}
+
+nginx-gw "nginx" {
     router = httpserver.server
+    nginx = nginx.main
     prefix = "/api/nginx/v1"
     middleware = [ "tokenauth" ]
 }
@@ -0,0 +1,166 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	// Module imports
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type accesslog struct {
+	provider.Task
+
+	label, format string
+	logger        Logger
+	router        Router
+}
+
+// entry is a record of a request
+type entry struct {
+	Time      time.Time `json:"time"`
+	Remote    string    `json:"remote,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Proto     string    `json:"proto"`
+	Prefix    string    `json:"prefix,omitempty"`
+	Status    int       `json:"status"`
+	Size      int       `json:"size"`
+	Duration  float64   `json:"duration"`
+	Token     string    `json:"token,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	combinedTime = "02/Jan/2006:15:04:05 -0700"
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func NewWithConfig(c Config) (Task, error) {
+	plugin := new(accesslog)
+	plugin.label = c.Label()
+	plugin.format = c.Format
+	plugin.logger = c.Logger.Task.(Logger)
+
+	// Register middleware with the label of the task
+	router := c.Router.Task.(Router)
+	plugin.router = router
+	if err := router.AddMiddleware(plugin.label, plugin.LogHandler); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return plugin, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (plugin *accesslog) String() string {
+	str := "<accesslog"
+	str += fmt.Sprintf(" label=%q", plugin.label)
+	str += fmt.Sprintf(" format=%q", plugin.format)
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (plugin *accesslog) Label() string {
+	return plugin.label
+}
+
+// Close removes the middleware from the router, when the task is removed
+// from the provider
+func (plugin *accesslog) Close() error {
+	return plugin.router.RemoveMiddleware(plugin.label)
+}
+
+// LogHandler is middleware which serves a request and then writes an
+// entry for the request to the logger
+func (plugin *accesslog) LogHandler(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// Serve the request, where the token name is set on the response by
+		// any middleware which follows, or in the context by middleware before
+		rw := util.NewResponseWriter(w)
+		fn(rw, r)
+		token := rw.Name
+		if token == "" {
+			token = context.ReqName(r)
+		}
+
+		// Write the entry
+		entry := entry{
+			Time:      start,
+			Remote:    remoteHost(r.RemoteAddr),
+			Method:    r.Method,
+			Path:      r.URL.RequestURI(),
+			Proto:     r.Proto,
+			Prefix:    context.ReqPrefix(r),
+			Status:    rw.Status,
+			Size:      rw.Size,
+			Duration:  time.Since(start).Seconds(),
+			Token:     token,
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		plugin.logger.Log(r.Context(), plugin.line(entry))
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// line returns an entry in the format of the access log. The combined
+// format is followed by the matched prefix and the duration in seconds
+func (plugin *accesslog) line(e entry) string {
+	if plugin.format == FormatJSON {
+		if data, err := json.Marshal(e); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %d %q %q %q %.6f",
+		dash(e.Remote), dash(e.Token), e.Time.Format(combinedTime),
+		e.Method+" "+e.Path+" "+e.Proto, e.Status, e.Size,
+		dash(e.Referer), dash(e.UserAgent), dash(e.Prefix), e.Duration,
+	)
+}
+
+// remoteHost returns the host from a remote address
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// dash returns "-" for an empty value
+func dash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
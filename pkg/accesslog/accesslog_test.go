@@ -0,0 +1,107 @@
+package accesslog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Module imports
+	accesslog "github.com/mutablelogic/terraform-provider-nginx/pkg/accesslog"
+	logger "github.com/mutablelogic/terraform-provider-nginx/pkg/logger"
+	metrics "github.com/mutablelogic/terraform-provider-nginx/pkg/metrics"
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+func Test_AccessLog_001(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "log")
+
+	// Create tasks, where the access logs are applied before middleware
+	// which sets the token name
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := provider.New(ctx, logger.Config{Format: logger.FormatJSON, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range []accesslog.Config{
+		{Label_: "json", Format: accesslog.FormatJSON},
+		{Label_: "combined"},
+	} {
+		config.Router = types.Task{Task: router}
+		config.Logger = types.Task{Task: logger}
+		if task, err := provider.New(ctx, config); err != nil {
+			t.Fatal(err)
+		} else {
+			t.Log(task)
+		}
+	}
+	if err := router.(Router).AddMiddleware("auth", func(fn http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			util.SetName(w, "test")
+			fn(w, r)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, metrics.Config{
+		Router:     types.Task{Task: router},
+		Middleware: []string{"json", "combined", "auth"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make a request
+	w := httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatal("Unexpected status", w.Code)
+	}
+
+	// Read the log entries
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected two entries, got %q", lines)
+	}
+	messages := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, record["msg"].(string))
+	}
+
+	// The innermost access log writes first
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(messages[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["method"] != "GET" || entry["path"] != "/metrics" || entry["prefix"] != "/" || entry["status"] != float64(200) || entry["token"] != "test" {
+		t.Error("Unexpected entry", entry)
+	}
+	if entry["size"].(float64) != float64(w.Body.Len()) {
+		t.Error("Unexpected size", entry["size"])
+	}
+	if !strings.HasPrefix(messages[0], "192.0.2.1 - test [") || !strings.Contains(messages[0], `"GET /metrics HTTP/1.1" 200 `) {
+		t.Error("Unexpected entry", messages[0])
+	}
+}
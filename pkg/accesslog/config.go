@@ -0,0 +1,76 @@
+package accesslog
+
+import (
+	"context"
+
+	// Module imports
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Config struct {
+	Label_ string        `hcl:"label,label" json:"label,omitempty"`      // Label for the task, which is also the name of the middleware
+	Format string        `hcl:"format,optional" json:"format,omitempty"` // Format of entries (combined, json)
+	Logger types.Task    `hcl:"logger,optional" json:"logger"`           // plugin.Logger
+	Router types.Task    `hcl:"router,optional" json:"router"`           // plugin.Router
+	Policy *types.Policy `hcl:"policy,block" json:"policy,omitempty"`    // Supervision policy for the task
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DefaultName    = "accesslog"
+	DefaultLabel   = DefaultName
+	FormatCombined = "combined"
+	FormatJSON     = "json"
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
+	// Check arguments
+	if _, ok := c.Router.Task.(Router); c.Router.Task == nil || !ok {
+		return nil, ErrBadParameter.With("router")
+	}
+	if _, ok := c.Logger.Task.(Logger); c.Logger.Task == nil || !ok {
+		return nil, ErrBadParameter.With("logger")
+	}
+
+	// Set configuration defaults
+	if c.Format == "" {
+		c.Format = FormatCombined
+	}
+
+	// Check parameters
+	if !util.IsIdentifier(c.Label()) {
+		return nil, ErrBadParameter.Withf("label: %q", c.Label())
+	}
+	if c.Format != FormatCombined && c.Format != FormatJSON {
+		return nil, ErrBadParameter.Withf("format: %q", c.Format)
+	}
+
+	// Return new task
+	return NewWithConfig(c)
+}
+
+func (c Config) Name() string {
+	return DefaultName
+}
+
+func (c Config) Label() string {
+	if c.Label_ == "" {
+		return DefaultLabel
+	} else {
+		return c.Label_
+	}
+}
@@ -0,0 +1,9 @@
+// accesslog plugin provides router middleware which records each request
+// served by a gateway to a logger task. Each entry contains the request
+// method, path, matched prefix, response status, bytes written, duration
+// and the name of the authenticated token, in combined log or JSON format.
+//
+// Gateways opt in by adding the label of the accesslog task to their
+// middleware list. When the access log is placed before the tokenauth
+// middleware, requests which fail authentication are also recorded.
+package accesslog
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	// Modules
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Client makes requests to the gateway APIs
+type Client struct {
+	endpoint  string
+	token     string
+	nginx     string
+	tokenauth string
+	client    *http.Client
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	schemeUnix         = "unix"
+	hostUnix           = "unix"
+	pathSeparator      = "/"
+	authorizationKey   = "Authorization"
+	authorizationType  = "Bearer"
+	defaultNginxPrefix = "/nginx/v1"     // Default prefix for the nginx-gateway
+	defaultTokenPrefix = "/tokenauth/v1" // Default prefix for the tokenauth-gateway
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// New creates a client for a base URL. The URL can include a path, or use
+// the "unix" scheme with the path to a unix socket
+func New(endpoint string, opts ...ClientOpt) (*Client, error) {
+	c := new(Client)
+	c.client = new(http.Client)
+	c.nginx = defaultNginxPrefix
+	c.tokenauth = defaultTokenPrefix
+
+	// Parse the endpoint
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, ErrBadParameter.With(err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return nil, ErrBadParameter.Withf("endpoint: %q", endpoint)
+		}
+		c.endpoint = strings.TrimSuffix(u.String(), pathSeparator)
+	case schemeUnix:
+		if u.Path == "" {
+			return nil, ErrBadParameter.Withf("endpoint: %q", endpoint)
+		}
+		c.endpoint = "http://" + hostUnix
+		c.client.Transport = unixTransport(u.Path)
+	default:
+		return nil, ErrBadParameter.Withf("endpoint: %q", endpoint)
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	// Return success
+	return c, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (c *Client) String() string {
+	str := "<client"
+	str += fmt.Sprintf(" endpoint=%q", c.endpoint)
+	str += fmt.Sprintf(" nginx=%q", c.nginx)
+	str += fmt.Sprintf(" tokenauth=%q", c.tokenauth)
+	if c.token != "" {
+		str += " token"
+	}
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// do makes a request, and decodes the response into out if not nil
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		if data, err := json.Marshal(in); err != nil {
+			return err
+		} else {
+			body = bytes.NewReader(data)
+		}
+	}
+
+	// Create the request
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set(util.ContentTypeKey, util.ContentTypeJSON)
+	}
+	if c.token != "" {
+		req.Header.Set(authorizationKey, authorizationType+" "+c.token)
+	}
+
+	// Make the request
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Decode any error
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+
+	// Decode the response
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	// Return success
+	return nil
+}
+
+// decodeError returns a ResponseError from an error response
+func decodeError(resp *http.Response) error {
+	var reason util.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reason); err != nil || reason.Reason == "" {
+		reason.Reason = http.StatusText(resp.StatusCode)
+	}
+	return newResponseError(resp.StatusCode, reason.Reason)
+}
+
+// unixTransport returns a transport which connects to a unix socket
+func unixTransport(path string) *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, schemeUnix, path)
+		},
+	}
+}
+
+// normalizePrefix adds a leading separator and removes any trailing separator.
+// An empty prefix refers to the base URL
+func normalizePrefix(prefix string) string {
+	if prefix = strings.Trim(prefix, pathSeparator); prefix == "" {
+		return ""
+	}
+	return pathSeparator + prefix
+}
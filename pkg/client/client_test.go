@@ -0,0 +1,211 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	// Modules
+	nginx "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx"
+	gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx-gateway"
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+	. "github.com/mutablelogic/terraform-provider-nginx/pkg/client"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TESTS
+
+func Test_Client_001(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost", "ftp://localhost/", "http://", "unix://"} {
+		if _, err := New(endpoint); !errors.Is(err, ErrBadParameter) {
+			t.Errorf("Expected error for endpoint %q, got %v", endpoint, err)
+		}
+	}
+	for _, endpoint := range []string{"http://localhost", "https://localhost/api/", "unix:///var/run/nginx-gateway.sock"} {
+		if client, err := New(endpoint); err != nil {
+			t.Errorf("Unexpected error for endpoint %q: %v", endpoint, err)
+		} else {
+			t.Log(client)
+		}
+	}
+}
+
+func Test_Client_002(t *testing.T) {
+	// Serve the gateway under a path, as if fronted by nginx, and check the token
+	handler := NewGateway(t)
+	server := httptest.NewServer(http.StripPrefix("/api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			util.ServeError(w, http.StatusUnauthorized)
+		} else {
+			handler.ServeHTTP(w, r)
+		}
+	})))
+	defer server.Close()
+
+	client, err := New(server.URL+"/api/", OptToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Create, get, list, patch and delete a configuration
+	if config, err := client.CreateConfig(ctx, "test", "server {}", true); err != nil {
+		t.Fatal(err)
+	} else if config.Name != "test" || !config.Enabled || config.Body != "server {}" {
+		t.Error("Unexpected config: ", config)
+	}
+	if _, err := client.CreateConfig(ctx, "test", "server {}", true); !errors.Is(err, ErrDuplicateEntry) {
+		t.Error("Expected ErrDuplicateEntry, got", err)
+	}
+	if config, err := client.GetConfig(ctx, "test"); err != nil {
+		t.Error(err)
+	} else if config.Body != "server {}" {
+		t.Error("Unexpected config: ", config)
+	}
+	if configs, err := client.ListConfigs(ctx); err != nil {
+		t.Error(err)
+	} else if len(configs) != 1 {
+		t.Error("Unexpected configs: ", configs)
+	}
+	if config, err := client.PatchConfig(ctx, "test", false); err != nil {
+		t.Error(err)
+	} else if config.Enabled {
+		t.Error("Unexpected config: ", config)
+	}
+	if err := client.DeleteConfig(ctx, "test"); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.GetConfig(ctx, "test"); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+
+	// Requests without a token should fail
+	client, err = New(server.URL + "/api")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := client.ListConfigs(ctx); !errors.Is(err, ErrNotAuthorized) {
+		t.Error("Expected ErrNotAuthorized, got", err)
+	} else if rerr := new(ResponseError); !errors.As(err, &rerr) || rerr.Status != http.StatusUnauthorized {
+		t.Error("Unexpected response error", err)
+	}
+}
+
+func Test_Client_003(t *testing.T) {
+	// Serve token responses
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tokenauth/v1/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/tokenauth/v1/":
+			util.ServeJSON(w, []map[string]any{{"name": "admin", "scopes": []string{"nginx:read"}, "access_time": "2022-01-01T00:00:00Z", "expires": "2023-01-01T00:00:00Z"}}, http.StatusOK, 0)
+		case r.Method == http.MethodPost && r.URL.Path == "/tokenauth/v1/test":
+			util.ServeJSON(w, "value", http.StatusCreated, 0)
+		case r.Method == http.MethodDelete && r.URL.Path == "/tokenauth/v1/test":
+			util.ServeEmpty(w, http.StatusOK)
+		default:
+			util.ServeError(w, http.StatusNotFound, "not found")
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if tokens, err := client.ListTokens(ctx); err != nil {
+		t.Error(err)
+	} else if len(tokens) != 1 || tokens[0].Name != "admin" || len(tokens[0].Scopes) != 1 || tokens[0].Time.IsZero() || tokens[0].Expires.IsZero() {
+		t.Error("Unexpected tokens: ", tokens)
+	}
+	if value, err := client.CreateToken(ctx, "test", time.Now().Add(time.Hour), "nginx:read"); err != nil {
+		t.Error(err)
+	} else if value != "value" {
+		t.Error("Unexpected value: ", value)
+	}
+	if err := client.RevokeToken(ctx, "test"); err != nil {
+		t.Error(err)
+	}
+	if err := client.RevokeToken(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func Test_Client_004(t *testing.T) {
+	// Serve the gateway on a unix socket
+	path := filepath.Join(t.TempDir(), "gateway.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: NewGateway(t)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := New("unix://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configs, err := client.ListConfigs(context.Background()); err != nil {
+		t.Error(err)
+	} else if len(configs) != 0 {
+		t.Error("Unexpected configs: ", configs)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+var (
+	reNginxPrefix = regexp.MustCompile(`^/nginx/v1`)
+)
+
+// NewGateway returns an nginx-gateway handler, backed by a temporary folder.
+// The gateway middleware allows all requests, so tests check the token
+func NewGateway(t *testing.T) http.Handler {
+	tmpdir := t.TempDir()
+	for _, path := range []string{"sites-available", "sites-enabled"} {
+		if err := os.Mkdir(filepath.Join(tmpdir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create tasks
+	ctx := context.Background()
+	provider := provider.New()
+	nginx, err := provider.New(ctx, nginx.Config{Path: tmpdir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := router.(plugin.Router).AddMiddleware("auth", func(fn http.HandlerFunc) http.HandlerFunc {
+		return fn
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reload := false
+	if _, err := provider.New(ctx, gateway.Config{Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}, Middleware: []string{"auth"}, Reload: &reload}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Return the router
+	return router.(http.Handler)
+}
@@ -0,0 +1,19 @@
+/*
+Package client provides a client for the nginx-gateway and tokenauth-gateway
+APIs. Create a client with a base URL for the server, and optionally a token
+for authentication:
+
+	client, err := client.New("http://localhost/", client.OptToken(token))
+	if err != nil {
+		// Handle error
+	}
+	configs, err := client.ListConfigs(ctx)
+
+The base URL can include a path, for a server which is fronted by nginx using
+FastCGI, or use the "unix" scheme to connect to a server listening on a unix
+socket, for example "unix:///var/run/nginx-gateway.sock". Error responses from
+the server are returned as a *ResponseError with the status code, which wraps
+ErrNotFound, ErrDuplicateEntry, ErrBadParameter, ErrNotAuthorized (when the
+request is not authenticated or not authorized) or ErrUnexpectedResponse.
+*/
+package client
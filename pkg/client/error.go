@@ -0,0 +1,62 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ResponseError is returned for an error response from the server, and
+// includes the status code and the reason for the error
+type ResponseError struct {
+	Status int    // Status code of the response
+	Reason string // Reason for the error
+	err    error
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// ErrNotAuthorized is wrapped by an error response when a request is not
+// authenticated or not authorized
+var ErrNotAuthorized = errors.New("ErrNotAuthorized")
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newResponseError returns an error for a status code and reason, which
+// wraps ErrNotFound, ErrDuplicateEntry, ErrBadParameter, ErrNotAuthorized
+// or ErrUnexpectedResponse
+func newResponseError(status int, reason string) *ResponseError {
+	err := &ResponseError{Status: status, Reason: reason}
+	switch status {
+	case http.StatusNotFound:
+		err.err = ErrNotFound.With(reason)
+	case http.StatusConflict:
+		err.err = ErrDuplicateEntry.With(reason)
+	case http.StatusBadRequest:
+		err.err = ErrBadParameter.With(reason)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		err.err = fmt.Errorf("%w: %s", ErrNotAuthorized, reason)
+	default:
+		err.err = ErrUnexpectedResponse.Withf("%d: %s", status, reason)
+	}
+	return err
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (e *ResponseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Config is an nginx configuration
+type Config struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Body    string `json:"body,omitempty"`
+}
+
+// configRequest is the body of a request to create or modify a configuration
+type configRequest struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Body    string `json:"body,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListConfigs returns all configurations, without the body
+func (c *Client) ListConfigs(ctx context.Context) ([]Config, error) {
+	var result []Config
+	if err := c.do(ctx, http.MethodGet, c.nginx+pathSeparator, nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetConfig returns a configuration, including the body
+func (c *Client) GetConfig(ctx context.Context, name string) (*Config, error) {
+	var result Config
+	if err := c.do(ctx, http.MethodGet, c.nginxPath(name), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateConfig creates a new configuration, and optionally enables it
+func (c *Client) CreateConfig(ctx context.Context, name, body string, enabled bool) (*Config, error) {
+	var result Config
+	if err := c.do(ctx, http.MethodPost, c.nginxPath(name), configRequest{Body: body, Enabled: &enabled}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PatchConfig enables or disables a configuration
+func (c *Client) PatchConfig(ctx context.Context, name string, enabled bool) (*Config, error) {
+	var result Config
+	if err := c.do(ctx, http.MethodPatch, c.nginxPath(name), configRequest{Enabled: &enabled}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteConfig removes a configuration
+func (c *Client) DeleteConfig(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.nginxPath(name), nil, nil)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (c *Client) nginxPath(name string) string {
+	return c.nginx + pathSeparator + url.PathEscape(name)
+}
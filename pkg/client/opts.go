@@ -0,0 +1,48 @@
+package client
+
+import (
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ClientOpt is an option which can be applied when creating a client
+type ClientOpt func(*Client) error
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// OptToken sets the token used for bearer authentication
+func OptToken(token string) ClientOpt {
+	return func(c *Client) error {
+		c.token = token
+		return nil
+	}
+}
+
+// OptTimeout sets the timeout for requests
+func OptTimeout(timeout time.Duration) ClientOpt {
+	return func(c *Client) error {
+		c.client.Timeout = timeout
+		return nil
+	}
+}
+
+// OptNginxPrefix sets the path prefix for the nginx-gateway, relative
+// to the base URL
+func OptNginxPrefix(prefix string) ClientOpt {
+	return func(c *Client) error {
+		c.nginx = normalizePrefix(prefix)
+		return nil
+	}
+}
+
+// OptTokenAuthPrefix sets the path prefix for the tokenauth-gateway,
+// relative to the base URL
+func OptTokenAuthPrefix(prefix string) ClientOpt {
+	return func(c *Client) error {
+		c.tokenauth = normalizePrefix(prefix)
+		return nil
+	}
+}
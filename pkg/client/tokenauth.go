@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Token is the name, scopes, last access time and expiry time for a token
+type Token struct {
+	Name    string    `json:"name"`
+	Scopes  []string  `json:"scopes,omitempty"`
+	Time    time.Time `json:"access_time"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// tokenRequest is the body of a request to create a token
+type tokenRequest struct {
+	Scopes  []string  `json:"scopes,omitempty"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	adminToken = "admin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListTokens returns all token names and their last access times
+func (c *Client) ListTokens(ctx context.Context) ([]Token, error) {
+	var result []Token
+	if err := c.do(ctx, http.MethodGet, c.tokenauth+pathSeparator, nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateToken creates a new token with a name, an expiry time (or zero if the
+// token does not expire) and optional scopes, such as "nginx:read", and returns
+// the token value
+func (c *Client) CreateToken(ctx context.Context, name string, expires time.Time, scopes ...string) (string, error) {
+	var result string
+	if err := c.do(ctx, http.MethodPost, c.tokenauthPath(name), tokenRequest{Scopes: scopes, Expires: expires}, &result); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// RevokeToken revokes a token with a name. The admin token is rotated
+// rather than revoked
+func (c *Client) RevokeToken(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.tokenauthPath(name), nil, nil)
+}
+
+// RotateAdminToken rotates the admin token, which requires the admin token.
+// The new value is not returned, and is read from the admin file on the server
+func (c *Client) RotateAdminToken(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, c.tokenauthPath(adminToken), nil, nil)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (c *Client) tokenauthPath(name string) string {
+	return c.tokenauth + pathSeparator + url.PathEscape(name)
+}
@@ -19,12 +19,13 @@ type contextType uint
 
 const (
 	contextNone contextType = iota
-	contextName
+	contextTaskName
 	contextLabel
 	contextPrefix
 	contextParams
 	contextAdmin
 	contextAddress
+	contextToken
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -35,7 +36,7 @@ func ContextForSignal(signals ...os.Signal) context.Context {
 		return nil
 	}
 
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Send message on channel when signal received
@@ -58,12 +59,15 @@ func WithPrefixParams(ctx context.Context, prefix string, params []string) conte
 	return context.WithValue(context.WithValue(ctx, contextParams, params), contextPrefix, prefix)
 }
 
+// WithName sets the name of the authenticated token in the context
 func WithName(ctx context.Context, name string) context.Context {
-	return context.WithValue(ctx, contextName, name)
+	return context.WithValue(ctx, contextToken, name)
 }
 
+// WithNameLabel sets the name and label of a task in the context, which is
+// distinct from the name of a token set with WithName
 func WithNameLabel(ctx context.Context, name, label string) context.Context {
-	return context.WithValue(context.WithValue(ctx, contextName, name), contextLabel, label)
+	return context.WithValue(context.WithValue(ctx, contextTaskName, name), contextLabel, label)
 }
 
 func WithAdmin(ctx context.Context, admin bool) context.Context {
@@ -78,7 +82,7 @@ func WithAddress(ctx context.Context, addr string) context.Context {
 // RETURN VALUES FROM CONTEXT
 
 func Name(ctx context.Context) string {
-	return contextString(ctx, contextName)
+	return contextString(ctx, contextTaskName)
 }
 
 func Label(ctx context.Context) string {
@@ -93,20 +97,29 @@ func Address(ctx context.Context) string {
 	return contextString(ctx, contextAddress)
 }
 
-func ReqParams(req *http.Request) []string {
-	if value, ok := req.Context().Value(contextParams).([]string); ok {
+func Prefix(ctx context.Context) string {
+	return contextString(ctx, contextPrefix)
+}
+
+func Params(ctx context.Context) []string {
+	if value, ok := ctx.Value(contextParams).([]string); ok {
 		return value
 	} else {
 		return nil
 	}
 }
 
+func ReqParams(req *http.Request) []string {
+	return Params(req.Context())
+}
+
 func ReqPrefix(req *http.Request) string {
-	return contextString(req.Context(), contextPrefix)
+	return Prefix(req.Context())
 }
 
+// ReqName returns the name of the token which authenticated a request
 func ReqName(req *http.Request) string {
-	return contextString(req.Context(), contextName)
+	return contextString(req.Context(), contextToken)
 }
 
 func ReqAdmin(req *http.Request) bool {
@@ -118,7 +131,7 @@ func ReqAdmin(req *http.Request) bool {
 
 func DumpContext(ctx context.Context, w io.Writer) {
 	fmt.Fprintf(w, "<context")
-	if value, ok := ctx.Value(contextName).(string); ok {
+	if value, ok := ctx.Value(contextTaskName).(string); ok {
 		fmt.Fprintf(w, " name=%q", value)
 	}
 	if value, ok := ctx.Value(contextLabel).(string); ok {
@@ -130,7 +143,10 @@ func DumpContext(ctx context.Context, w io.Writer) {
 	if value, ok := ctx.Value(contextParams).([]string); ok {
 		fmt.Fprintf(w, " params=%q", value)
 	}
-	if value, ok := ctx.Value(contextBool).(bool); ok {
+	if value, ok := ctx.Value(contextToken).(string); ok {
+		fmt.Fprintf(w, " token=%q", value)
+	}
+	if value, ok := ctx.Value(contextAdmin).(bool); ok {
 		fmt.Fprintf(w, " admin=%v", value)
 	}
 	if value, ok := ctx.Value(contextAddress).(string); ok {
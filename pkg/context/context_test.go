@@ -0,0 +1,31 @@
+package context_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+)
+
+func Test_Context_001(t *testing.T) {
+	// The task name and the token name are stored separately
+	ctx := WithNameLabel(context.Background(), "task", "label")
+	ctx = WithName(ctx, "token")
+	if name := Name(ctx); name != "task" {
+		t.Error("Unexpected task name", name)
+	}
+	if label := Label(ctx); label != "label" {
+		t.Error("Unexpected label", label)
+	}
+
+	// Setting the task name does not change the token name
+	ctx = WithNameLabel(ctx, "other", "label")
+	if name := Name(ctx); name != "other" {
+		t.Error("Unexpected task name", name)
+	}
+	if name := ReqName(httptest.NewRequest("GET", "/", nil).WithContext(ctx)); name != "token" {
+		t.Error("Unexpected token name", name)
+	}
+}
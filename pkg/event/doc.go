@@ -1,6 +1,7 @@
 /*
 Package `event` provides events and subscriptions. Event messages are created with
-a key/value pair using `NewEvent`, or an error with `NewError`. Events can be
+a key/value pair using `NewEvent`, or an error with `NewError`. An error for a
+specific operation can be created with a key using `NewKeyError`. Events can be
 emitted on a channel using the `Emit` method, which returns false if the channel
 is full.
 
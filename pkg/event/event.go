@@ -29,6 +29,12 @@ func NewError(err error) Event {
 	return &event{nil, nil, err}
 }
 
+// NewKeyError is called to create an error event with a key, which
+// describes the operation which failed
+func NewKeyError(key any, err error) Event {
+	return &event{key, nil, err}
+}
+
 /////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -50,10 +50,23 @@ func (p *PubSub) Sub() <-chan Event {
 }
 
 // Unsub is called to unsubscribe a specific channel. Will panic
-// if the channel is not subscribed
+// if the channel is not subscribed. Events are discarded whilst waiting
+// for any event being emitted, so that a subscriber can unsubscribe
+// whilst an event is being sent to it
 func (p *PubSub) Unsub(ch <-chan Event) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+			case <-done:
+				return
+			}
+		}
+	}()
 	p.Lock()
 	defer p.Unlock()
+	close(done)
 	for i := range p.ch {
 		if ch == p.ch[i] {
 			close(p.ch[i])
@@ -65,25 +78,23 @@ func (p *PubSub) Unsub(ch <-chan Event) {
 }
 
 // Emit can be called to send an event to all subscribers,
-// and returns true if the event was sent to all channels
+// and returns true if the event was sent to all channels. Channels
+// cannot be subscribed or unsubscribed whilst an event is emitted
 func (p *PubSub) Emit(e Event) bool {
+	p.Lock()
+	defer p.Unlock()
 	result := true
-	for i, ch := range p.ch {
+	for _, ch := range p.ch {
 		if ch == nil {
 			// do nothing if channel is closed
 		} else if e == nil {
 			close(ch)
-			p.Lock()
-			p.ch[i] = nil
-			p.Unlock()
 		} else if done := e.Emit(ch); !done {
 			result = false
 		}
 	}
 	if e == nil {
-		p.Lock()
 		p.ch = nil
-		p.Unlock()
 	}
 	return result
 }
@@ -2,7 +2,9 @@ package event_test
 
 import (
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	// Namespace imports
 	. "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
@@ -58,7 +60,7 @@ func Test_PubSub_003(t *testing.T) {
 
 	p := new(PubSub)
 	n := 100
-	m := 0
+	m := int64(0)
 
 	for r := 0; r < 5; r++ {
 		ch := p.Sub()
@@ -67,7 +69,7 @@ func Test_PubSub_003(t *testing.T) {
 			defer wg.Done()
 			for evt := range ch {
 				t.Log("Received", evt, "on channel", i)
-				m += 1
+				atomic.AddInt64(&m, 1)
 			}
 		}(r)
 	}
@@ -86,7 +88,32 @@ func Test_PubSub_003(t *testing.T) {
 	wg.Wait()
 
 	// Check number of received events
-	if n*5 != m {
+	if int64(n*5) != atomic.LoadInt64(&m) {
 		t.Error("Expected", n, "events, got", m)
 	}
 }
+
+func Test_PubSub_004(t *testing.T) {
+	p := new(PubSub)
+
+	// Emit events whilst subscribers receive one event and then unsubscribe
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			p.Emit(NewEvent(t.Name(), i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ch := p.Sub()
+		select {
+		case <-ch:
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected event")
+		}
+		p.Unsub(ch)
+	}
+	<-done
+	p.Emit(nil)
+}
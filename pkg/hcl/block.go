@@ -6,7 +6,9 @@ import (
 	"time"
 
 	// Module imports
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	"github.com/zclconf/go-cty/cty"
 
 	// Namespace imports
@@ -29,11 +31,16 @@ const (
 )
 
 var (
-	typeString     = reflect.TypeOf("")
-	typeListString = reflect.TypeOf([]string{})
-	typeDuration   = reflect.TypeOf(time.Second)
-	typeTask       = reflect.TypeOf((*Task)(nil)).Elem()
-	typeAny        = reflect.TypeOf((*interface{})(nil)).Elem()
+	typeString        = reflect.TypeOf("")
+	typeListString    = reflect.TypeOf([]string{})
+	typeMapString     = reflect.TypeOf(map[string]string{})
+	typeDuration      = reflect.TypeOf(time.Second)
+	typeTypesDuration = reflect.TypeOf(types.Duration(0))
+	typeTask          = reflect.TypeOf((*Task)(nil)).Elem()
+	typeTypesTask     = reflect.TypeOf(types.Task{})
+	typeListTask      = reflect.TypeOf([]types.Task{})
+	typeCtyValue      = reflect.TypeOf(cty.Value{})
+	typeAny           = reflect.TypeOf((*interface{})(nil)).Elem()
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -51,6 +58,24 @@ func SpecForType(t reflect.Type, tag, name string) (hcldec.Spec, error) {
 	}
 }
 
+// SchemaForType returns the block header schema for a block type, which
+// is used to determine the source ranges for the blocks
+func SchemaForType(t reflect.Type, tag, name string) (hcl.BlockHeaderSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return hcl.BlockHeaderSchema{}, ErrBadParameter.With("Invalid type:", t)
+	}
+	schema := hcl.BlockHeaderSchema{Type: name}
+	for i := 0; i < t.NumField(); i++ {
+		if name, kind := tagNameKind(t.Field(i).Tag, tag); name != "" && kind == hclTagLabel {
+			schema.LabelNames = append(schema.LabelNames, name)
+		}
+	}
+	return schema, nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
@@ -91,7 +116,7 @@ func specForBlock(t reflect.Type, tag, name string, min, max int) (hcldec.Spec,
 				}
 			} else {
 				// Exactly one block
-				if spec, err := specForBlock(field.Type.Elem(), tag, name, 1, 1); err != nil {
+				if spec, err := specForBlock(field.Type, tag, name, 1, 1); err != nil {
 					return nil, err
 				} else {
 					result = append(result, spec)
@@ -162,13 +187,30 @@ func typeForAttr(t reflect.Type) cty.Type {
 		return cty.String
 	case typeListString:
 		return cty.List(cty.String)
-	case typeDuration:
+	case typeMapString:
+		return cty.Map(cty.String)
+	case typeDuration, typeTypesDuration:
 		return cty.DynamicPseudoType
-	case typeTask:
+	case typeTask, typeTypesTask:
 		return cty.DynamicPseudoType
-	case typeAny:
+	case typeListTask:
+		return cty.List(cty.DynamicPseudoType)
+	case typeCtyValue, typeAny:
 		return cty.DynamicPseudoType
 	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return cty.Bool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cty.Number
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cty.Number
+	case reflect.Float32, reflect.Float64:
+		return cty.Number
+	case reflect.Ptr:
+		// Optional attributes can be a pointer, which is nil when not set
+		return typeForAttr(t.Elem())
+	}
 	// By default, return NilType for unsupported types
 	return cty.NilType
 }
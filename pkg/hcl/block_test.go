@@ -12,7 +12,7 @@ import (
 )
 
 func Test_Block_001(t *testing.T) {
-	if spec, err := SpecForType(reflect.TypeOf(httpserver.ServerConfig{}), TagName, "server"); err != nil {
+	if spec, err := SpecForType(reflect.TypeOf(httpserver.Config{}), TagName, "server"); err != nil {
 		t.Fatal(err)
 	} else {
 		t.Log(spec)
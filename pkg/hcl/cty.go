@@ -4,6 +4,7 @@ import (
 	"math"
 	"math/big"
 	"reflect"
+	"time"
 
 	// Modules
 	"github.com/hashicorp/go-multierror"
@@ -34,8 +35,17 @@ func FromCtyValue(val cty.Value, target interface{}) error {
 func fromCtyValue(val cty.Value, target reflect.Value) error {
 	t := val.Type()
 
-	// Set target to element
+	// Set target to element, allocating a new value where the pointer is nil
 	if target.Kind() == reflect.Ptr {
+		if target.IsNil() && target.CanSet() {
+			if val.IsNull() {
+				return nil
+			} else if t.IsListType() && val.IsKnown() && val.LengthInt() == 0 {
+				// No block has been defined
+				return nil
+			}
+			target.Set(reflect.New(target.Type().Elem()))
+		}
 		target = target.Elem()
 	}
 	if !target.CanSet() {
@@ -46,10 +56,18 @@ func fromCtyValue(val cty.Value, target reflect.Value) error {
 		}
 	}
 
+	// cty.Value targets are set without conversion
+	if target.Type() == typeCtyValue {
+		target.Set(reflect.ValueOf(val))
+		return nil
+	}
+
 	// Where value is nil, return zero-value for type
 	if val.IsNull() {
 		target.Set(reflect.Zero(target.Type()))
 		return nil
+	} else if !val.IsKnown() {
+		return ErrBadParameter.With("value is not known")
 	}
 
 	switch t {
@@ -96,6 +114,20 @@ func fromCtyNumber(val cty.Value, target reflect.Value) error {
 		return fromCtyNumberUInt(val, target)
 	case reflect.Float32, reflect.Float64:
 		return fromCtyNumberFloat(val, target)
+	case reflect.Interface:
+		if target.Type() != typeAny {
+			return ErrBadParameter.Withf("number type is required, not %v", target.Kind())
+		}
+		// Use an integer type where the number is an integer within range
+		bf := val.AsBigFloat()
+		if iv, accuracy := bf.Int64(); accuracy == big.Exact {
+			target.Set(reflect.ValueOf(iv))
+		} else if uv, accuracy := bf.Uint64(); accuracy == big.Exact {
+			target.Set(reflect.ValueOf(uv))
+		} else {
+			return fromCtyNumberFloat(val, target)
+		}
+		return nil
 	default:
 		return ErrBadParameter.Withf("number type is required, not %v", target.Kind())
 	}
@@ -171,6 +203,15 @@ func fromCtyNumberFloat(val cty.Value, target reflect.Value) error {
 
 func fromCtyString(val cty.Value, target reflect.Value) error {
 	switch {
+	case target.Type() == typeDuration || target.Type() == typeTypesDuration:
+		if d, err := time.ParseDuration(val.AsString()); err != nil {
+			return ErrBadParameter.Withf("invalid duration %q", val.AsString())
+		} else {
+			target.SetInt(int64(d))
+		}
+	case target.Type() == typeTypesTask:
+		// Set the reference to a task, which is resolved later
+		target.FieldByName("Ref").SetString(val.AsString())
 	case target.Kind() == reflect.String:
 		target.SetString(val.AsString())
 	case target.Type() == typeAny:
@@ -215,6 +256,18 @@ func fromCtyList(val cty.Value, target reflect.Value) error {
 				return false
 			})
 		}
+	case reflect.Struct:
+		// A list of blocks of which zero or one is expected
+		switch val.LengthInt() {
+		case 0:
+			target.Set(reflect.Zero(target.Type()))
+		case 1:
+			if err := fromCtyValue(val.Index(cty.NumberIntVal(0)), target); err != nil {
+				result = multierror.Append(result, err)
+			}
+		default:
+			result = multierror.Append(result, ErrBadParameter.With("a single block is required"))
+		}
 	default:
 		result = multierror.Append(result, ErrBadParameter.Withf("list type is required, not %v", target.Kind()))
 	}
@@ -282,15 +335,17 @@ func fromCtyTuple(val cty.Value, target reflect.Value) error {
 		return ErrBadParameter.Withf("struct type is required, not %v", target.Kind())
 	}
 
+	// Where fields are tagged, only the tagged fields are set from the tuple,
+	// otherwise all fields are set in order
+	fields := tupleFields(target.Type())
 	elemTypes := val.Type().TupleElementTypes()
-	fieldCount := target.Type().NumField()
-	if fieldCount != len(elemTypes) {
-		return ErrBadParameter.Withf("a tuple of %d elements is required", fieldCount)
+	if len(fields) != len(elemTypes) {
+		return ErrBadParameter.Withf("a tuple of %d elements is required", len(fields))
 	}
 
 	for i := range elemTypes {
 		ev := val.Index(cty.NumberIntVal(int64(i)))
-		targetField := target.Field(i)
+		targetField := target.Field(fields[i])
 		if err := fromCtyValue(ev, targetField); err != nil {
 			result = multierror.Append(result, err)
 		}
@@ -299,3 +354,19 @@ func fromCtyTuple(val cty.Value, target reflect.Value) error {
 	// Return success
 	return result
 }
+
+// tupleFields returns the indexes of the fields which are set from a tuple
+func tupleFields(t reflect.Type) []int {
+	var tagged, all []int
+	for i := 0; i < t.NumField(); i++ {
+		if name, _ := tagNameKind(t.Field(i).Tag, TagName); name != "" {
+			tagged = append(tagged, i)
+		}
+		all = append(all, i)
+	}
+	if len(tagged) > 0 {
+		return tagged
+	} else {
+		return all
+	}
+}
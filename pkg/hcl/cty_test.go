@@ -280,14 +280,14 @@ func Test_Cty_005(t *testing.T) {
 func Test_Cty_006(t *testing.T) {
 	var tests = []struct {
 		In  cty.Value
-		Out httpserver.ServerConfig
+		Out httpserver.Config
 	}{
-		{cty.NilVal, httpserver.ServerConfig{}},
-		{cty.ObjectVal(map[string]cty.Value{"Label": cty.StringVal("test")}), httpserver.ServerConfig{Label: "test"}},
+		{cty.NilVal, httpserver.Config{}},
+		{cty.ObjectVal(map[string]cty.Value{"Label_": cty.StringVal("test")}), httpserver.Config{Label_: "test"}},
 	}
 	for i, test := range tests {
 		t.Run(fmt.Sprint(i), func(t *testing.T) {
-			var out httpserver.ServerConfig
+			var out httpserver.Config
 			if err := FromCtyValue(test.In, &out); err != nil {
 				t.Error(err)
 			} else if reflect.DeepEqual(out, test.Out) != true {
@@ -1,13 +1,17 @@
 package hcl
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 
 	// Module imports
-
-	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
@@ -25,8 +29,13 @@ type decoder struct {
 	spec    hcldec.TupleSpec
 	blocks  []string
 	plugins map[string]TaskPlugin
+	schema  *hcl.BodySchema
 	vars    map[string]cty.Value
+	setvars map[string]string
 	fns     map[string]function.Function
+	filesys fs.FS
+	files   map[string]*hcl.File
+	ranges  map[TaskPlugin]hcl.Range
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -39,7 +48,9 @@ func NewDecoder() *decoder {
 	d.tag = TagName
 	d.plugins = make(map[string]TaskPlugin)
 	d.vars = make(map[string]cty.Value)
+	d.setvars = make(map[string]string)
 	d.fns = make(map[string]function.Function)
+	d.schema = new(hcl.BodySchema)
 
 	// Register the var block type
 	d.MustRegister(varblock{})
@@ -66,12 +77,15 @@ func (d *decoder) Register(plugin TaskPlugin) error {
 		return ErrDuplicateEntry.Withf("%q", name)
 	}
 
-	// Append spec
+	// Append spec and schema
 	if spec, err := SpecForType(reflect.TypeOf(plugin), d.tag, name); err != nil {
 		return err
+	} else if schema, err := SchemaForType(reflect.TypeOf(plugin), d.tag, name); err != nil {
+		return err
 	} else {
 		d.blocks = append(d.blocks, name)
 		d.spec = append(d.spec, spec)
+		d.schema.Blocks = append(d.schema.Blocks, schema)
 		d.plugins[name] = plugin
 	}
 
@@ -79,12 +93,36 @@ func (d *decoder) Register(plugin TaskPlugin) error {
 	return nil
 }
 
-// Parse a file
-func (d *decoder) Parse(filesys fs.FS, path string) ([]TaskPlugin, error) {
-	body, err := Parse(filesys, path)
+// Parse files and folders, and return the plugins in the order they are
+// defined. Any errors are returned as hcl.Diagnostics with the source range
+// of the block. References to other blocks are set as "name.label" and are
+// resolved by the provider
+func (d *decoder) Parse(filesys fs.FS, paths ...string) ([]TaskPlugin, error) {
+	d.vars = make(map[string]cty.Value)
+	d.ranges = make(map[TaskPlugin]hcl.Range)
+
+	// Parse the files
+	bodies, vars, files, err := parse(filesys, paths...)
+	d.files = files
 	if err != nil {
 		return nil, err
 	}
+	body := hcl.MergeFiles(bodies)
+
+	// Set functions, where relative paths are resolved against the directory
+	// of the file which calls the function, or otherwise the first path
+	if len(paths) > 0 {
+		base := paths[0]
+		if info, err := fs.Stat(filesys, strings.TrimPrefix(base, string(os.PathSeparator))); err == nil && !info.IsDir() {
+			base = filepath.Dir(base)
+		}
+		d.setFunctions(filesys, base)
+	}
+
+	// Set variables
+	if diags := d.decodeVars(bodies, vars); diags.HasErrors() {
+		return nil, diags
+	}
 
 	// Create references for variables in the body
 	refs := NewRefs()
@@ -92,47 +130,126 @@ func (d *decoder) Parse(filesys fs.FS, path string) ([]TaskPlugin, error) {
 		return nil, err
 	}
 
-	// Decode the body
-	var plugins []TaskPlugin
-	var result error
-	if value, diags := hcldec.Decode(body, d.spec, refs.Context()); diags.HasErrors() {
+	// Obtain the blocks in order to report source ranges
+	content, _, diags := body.PartialContent(d.schema)
+	if diags.HasErrors() {
 		return nil, diags
-	} else {
-		// Convert cty.Value into configuration objects
-		value.ForEachElement(func(key, tuple cty.Value) bool {
-			// Obtain the index of the block
-			var i int
-			if err := FromCtyValue(key, &i); err != nil {
-				result = multierror.Append(result, err)
-				return true
-			} else if i < 0 || i >= len(d.blocks) {
-				result = multierror.Append(result, ErrInternalAppError)
-				return true
-			}
+	}
 
-			// Obtain the plugin
-			proto, exists := d.plugins[d.blocks[i]]
-			if !exists {
-				result = multierror.Append(result, ErrInternalAppError)
-				return true
-			}
+	// Decode each file in turn
+	ctx := refs.Context(d.vars)
+	ctx.Functions = d.fns
+	values := make([]cty.Value, 0, len(bodies))
+	for _, file := range bodies {
+		value, diags := hcldec.Decode(file.Body, d.spec, d.context(ctx, file.Body.MissingItemRange().Filename))
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		values = append(values, value)
+	}
 
-			return tuple.ForEachElement(func(_, tuple cty.Value) bool {
-				// Tuple should be iteratable for the fields
+	// Convert cty.Value into configuration objects, where the blocks of each
+	// type are in the same order as the merged body
+	var plugins []TaskPlugin
+	for i, name := range d.blocks {
+		if name == varName {
+			continue
+		}
+		proto := d.plugins[name]
+		blocks := content.Blocks.OfType(name)
+		j := 0
+		for _, value := range values {
+			value.Index(cty.NumberIntVal(int64(i))).ForEachElement(func(_, tuple cty.Value) bool {
 				plugin := fromPrototype(proto)
+				if j < len(blocks) {
+					d.ranges[plugin] = blocks[j].DefRange
+				}
+				j++
 				if err := FromCtyValue(tuple, plugin); err != nil {
-					result = multierror.Append(result, fmt.Errorf("%w: %q", err, plugin.Name()))
-					return true
+					diags = append(diags, d.NewDiagnostic(plugin, err))
 				} else {
 					plugins = append(plugins, plugin)
 				}
 				return false
 			})
-		})
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
 	}
 
 	// Return success
-	return plugins, result
+	return plugins, nil
+}
+
+// Files returns the files parsed, keyed by path
+func (d *decoder) Files() map[string]*hcl.File {
+	return d.files
+}
+
+// NewDiagnostic returns an error diagnostic for a plugin, with the source
+// range of the block which defined the plugin
+func (d *decoder) NewDiagnostic(plugin TaskPlugin, err error) *hcl.Diagnostic {
+	diag := &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  fmt.Sprintf("Invalid %q block", plugin.Name()),
+		Detail:   err.Error(),
+	}
+	if rng, exists := d.ranges[plugin]; exists {
+		diag.Subject = rng.Ptr()
+	}
+	return diag
+}
+
+// WriteDiagnostics writes an error to a writer, including the source
+// for any hcl.Diagnostics
+func (d *decoder) WriteDiagnostics(w io.Writer, err error) error {
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		return hcl.NewDiagnosticTextWriter(w, d.files, 0, false).WriteDiagnostics(diags)
+	} else {
+		_, err := fmt.Fprintln(w, err)
+		return err
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// decodeVars sets variables from the var blocks in each file, and then
+// binds values to the variables
+func (d *decoder) decodeVars(bodies []*hcl.File, vars hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	var blocks []*varblock
+	spec := hcldec.TupleSpec{d.spec[0]}
+	ctx := &hcl.EvalContext{Functions: d.fns}
+	for _, file := range bodies {
+		value, _, diags_ := hcldec.PartialDecode(file.Body, spec, d.context(ctx, file.Body.MissingItemRange().Filename))
+		if diags_.HasErrors() {
+			return diags_
+		}
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{d.schema.Blocks[0]},
+		})
+		value.Index(cty.NumberIntVal(0)).ForEachElement(func(key, tuple cty.Value) bool {
+			v := new(varblock)
+			if i, _ := key.AsBigFloat().Int64(); int(i) < len(content.Blocks) {
+				v.rng = content.Blocks[i].DefRange
+			}
+			if err := FromCtyValue(tuple, v); err != nil {
+				diags = append(diags, v.NewDiagnostic("Invalid variable", err.Error()))
+			} else {
+				blocks = append(blocks, v)
+			}
+			return false
+		})
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+
+	// Bind values to the variables
+	return d.bindVars(blocks, vars)
 }
 
 /////////////////////////////////////////////////////////////////////
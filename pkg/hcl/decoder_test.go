@@ -7,8 +7,15 @@ import (
 
 	// Module import
 	httpserver "github.com/mutablelogic/terraform-provider-nginx/pkg/httpserver"
+	nginx "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx"
+	nginxgw "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx-gateway"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
+	tokenauthgw "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth-gateway"
+	"github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 
 	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
 	. "github.com/mutablelogic/terraform-provider-nginx/pkg/hcl"
 )
 
@@ -18,19 +25,398 @@ func Test_Decoder_001(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Create a HCL decoder and register httpserver and router
+	// Create a HCL decoder and register the plugins
 	decoder := NewDecoder()
-	decoder.Register(httpserver.ServerConfig{})
-	decoder.Register(httpserver.RouterConfig{})
+	for _, plugin := range []TaskPlugin{httpserver.Config{}, router.Config{}, tokenauth.Config{}, tokenauthgw.Config{}, nginx.Config{}, nginxgw.Config{}} {
+		if err := decoder.Register(plugin); err != nil {
+			t.Fatal(err)
+		}
+	}
 
 	// Parse HCL to get the plugins
 	plugins, err := decoder.Parse(os.DirFS("/"), filepath.Join(wd, HCL_TESTS))
 	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
 		t.Fatal(err)
 	}
 
-	// Describe the plugins. References to variables and tasks have not yet been resolved
+	// Describe the plugins. References to tasks have not yet been resolved
 	for _, plugin := range plugins {
 		t.Log(plugin.Name(), "=>", plugin)
 	}
+	if len(plugins) != 5 {
+		t.Errorf("Unexpected number of plugins: %d", len(plugins))
+	}
+
+	// Check variables and references have been set
+	for _, plugin := range plugins {
+		switch plugin := plugin.(type) {
+		case *httpserver.Config:
+			if plugin.Addr != ":80" {
+				t.Errorf("Unexpected listen value: %q", plugin.Addr)
+			}
+		case *tokenauth.Config:
+			if plugin.Delta.String() != "30s" {
+				t.Errorf("Unexpected delta value: %v", plugin.Delta)
+			}
+		case *nginxgw.Config:
+			if plugin.Router.Ref != "httpserver.server" || plugin.Nginx.Ref != "nginx.main" {
+				t.Errorf("Unexpected references: %q, %q", plugin.Router.Ref, plugin.Nginx.Ref)
+			}
+		}
+	}
+}
+
+func Test_Decoder_002(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "undefined.hcl"), []byte(`
+httpserver "a" {
+    listen = var.undefined
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := NewDecoder()
+	decoder.MustRegister(httpserver.Config{})
+	decoder.MustRegister(router.Config{})
+	if _, err := decoder.Parse(os.DirFS("/"), dir); err == nil {
+		t.Error("Expected error for undefined variable")
+	} else {
+		t.Log(err)
+	}
+}
+
+func Test_Decoder_003(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "order.hcl"), []byte(`
+httpserver "main" {
+    router = router.main
+	timeout = "5s"
+}
+
+router "main" {}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := NewDecoder()
+	decoder.MustRegister(httpserver.Config{})
+	decoder.MustRegister(router.Config{})
+	plugins, err := decoder.Parse(os.DirFS("/"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 2 {
+		t.Fatal("Unexpected number of plugins")
+	}
+	if plugins[0].Name() != "httpserver" || plugins[1].Name() != "router" {
+		t.Error("Unexpected order", plugins)
+	}
+	if plugin := plugins[0].(*httpserver.Config); plugin.Timeout != types.Duration(5e9) || plugin.Router.Ref != "router.main" {
+		t.Error("Unexpected value", plugin)
+	}
+}
+
+func Test_Decoder_004(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.hcl"), []byte(`
+var "listen" {
+    type = "string"
+    default = ":80"
+}
+
+var "timeout" {
+    type = "string"
+}
+
+httpserver "main" {
+    listen = var.listen
+    timeout = var.timeout
+}
+
+router "main" {}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(vars map[string]string) (*httpserver.Config, error) {
+		decoder := NewDecoder()
+		decoder.MustRegister(httpserver.Config{})
+		decoder.MustRegister(router.Config{})
+		for name, value := range vars {
+			if err := decoder.SetVar(name, value); err != nil {
+				return nil, err
+			}
+		}
+		plugins, err := decoder.Parse(os.DirFS("/"), dir)
+		if err != nil {
+			return nil, err
+		}
+		return plugins[0].(*httpserver.Config), nil
+	}
+
+	// Required variable has no value
+	if _, err := parse(nil); err == nil {
+		t.Error("Expected error for missing variable")
+	}
+
+	// Environment variables override defaults
+	t.Setenv(EnvVarPrefix+"timeout", "10s")
+	t.Setenv(EnvVarPrefix+"listen", ":8080")
+	if plugin, err := parse(nil); err != nil {
+		t.Error(err)
+	} else if plugin.Addr != ":8080" || plugin.Timeout != types.Duration(10e9) {
+		t.Error("Unexpected value", plugin)
+	}
+
+	// SetVar overrides environment variables
+	if plugin, err := parse(map[string]string{"listen": ":8081"}); err != nil {
+		t.Error(err)
+	} else if plugin.Addr != ":8081" {
+		t.Error("Unexpected value", plugin)
+	}
+
+	// Variable files override SetVar
+	if err := os.WriteFile(filepath.Join(dir, "main.vars.hcl"), []byte(`
+listen = ":8082"
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if plugin, err := parse(map[string]string{"listen": ":8081"}); err != nil {
+		t.Error(err)
+	} else if plugin.Addr != ":8082" {
+		t.Error("Unexpected value", plugin)
+	}
+
+	// Undeclared variables are an error
+	if _, err := parse(map[string]string{"other": "value"}); err == nil {
+		t.Error("Expected error for undeclared variable")
+	}
+
+	// Values are checked against the type
+	if err := os.WriteFile(filepath.Join(dir, "main.vars.hcl"), []byte(`
+listen = [ ":8082" ]
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parse(nil); err == nil {
+		t.Error("Expected error for invalid type")
+	} else {
+		t.Log(err)
+	}
+}
+
+func Test_Decoder_005(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.hcl"), []byte(`
+httpserver "main" {
+    listen = templatefile("listen.tpl", { port = 8080 })
+    timeout = duration(90)
+}
+
+tokenauth "main" {
+    path = lower(env("TEST_DECODER_PATH"))
+    file = trimspace(file("file.txt"))
+    delta = duration("1m30s")
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(dir, "listen.tpl"), []byte(`${upper("localhost")}:${port}`), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("tokens.json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_DECODER_PATH", "/VAR/LIB")
+
+	decoder := NewDecoder()
+	decoder.MustRegister(httpserver.Config{})
+	decoder.MustRegister(tokenauth.Config{})
+	plugins, err := decoder.Parse(os.DirFS("/"), filepath.Join(dir, "main.hcl"))
+	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
+		t.Fatal(err)
+	} else if len(plugins) != 2 {
+		t.Fatal("Unexpected number of plugins")
+	}
+	if plugin := plugins[0].(*httpserver.Config); plugin.Addr != "LOCALHOST:8080" || plugin.Timeout != types.Duration(90e9) {
+		t.Error("Unexpected value", plugin)
+	}
+	if plugin := plugins[1].(*tokenauth.Config); plugin.Path != "/var/lib" || plugin.File != "tokens.json" || plugin.Delta != 90e9 {
+		t.Error("Unexpected value", plugin)
+	}
+
+	// Invalid duration
+	if err := os.WriteFile(filepath.Join(dir, "main.hcl"), []byte(`
+httpserver "main" {
+    timeout = duration("ten seconds")
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err := decoder.Parse(os.DirFS("/"), dir); err == nil {
+		t.Error("Expected error for invalid duration")
+	} else {
+		t.Log(err)
+	}
+}
+
+func Test_Decoder_006(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.hcl"), []byte(`
+router "main" {
+    policy {
+        restart = "on-failure"
+        max_restarts = 3
+        backoff = "500ms"
+    }
+}
+
+router "other" {}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := NewDecoder()
+	decoder.MustRegister(router.Config{})
+	plugins, err := decoder.Parse(os.DirFS("/"), dir)
+	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
+		t.Fatal(err)
+	} else if len(plugins) != 2 {
+		t.Fatal("Unexpected number of plugins")
+	}
+	if policy := plugins[0].(*router.Config).Policy; policy == nil || policy.Restart != "on-failure" || policy.MaxRestarts != 3 || policy.Backoff != types.Duration(5e8) {
+		t.Error("Unexpected policy", policy)
+	}
+	if policy := plugins[1].(*router.Config).Policy; policy != nil {
+		t.Error("Unexpected policy", policy)
+	}
+}
+
+func Test_Decoder_007(t *testing.T) {
+	// Files in two folders, which read files relative to their own folder
+	a, b := t.TempDir(), t.TempDir()
+	for path, data := range map[string]string{
+		filepath.Join(a, "main.hcl"): `
+tokenauth "a" {
+    file = trimspace(file("file.txt"))
+}
+`,
+		filepath.Join(a, "file.txt"): "a.json\n",
+		filepath.Join(b, "main.hcl"): `
+tokenauth "b" {
+    file = trimspace(file("file.txt"))
+    path = templatefile("tpl/path.tpl", {})
+}
+`,
+		filepath.Join(b, "file.txt"):        "b.json\n",
+		filepath.Join(b, "tpl", "path.tpl"): `${trimspace(file("path.txt"))}`,
+		filepath.Join(b, "tpl", "path.txt"): "/var/lib\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	decoder := NewDecoder()
+	decoder.MustRegister(tokenauth.Config{})
+	plugins, err := decoder.Parse(os.DirFS("/"), a, b)
+	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
+		t.Fatal(err)
+	} else if len(plugins) != 2 {
+		t.Fatal("Unexpected number of plugins")
+	}
+	for _, plugin := range plugins {
+		plugin := plugin.(*tokenauth.Config)
+		if plugin.File != plugin.Label()+".json" {
+			t.Error("Unexpected value", plugin)
+		}
+		if plugin.Label() == "b" && plugin.Path != "/var/lib" {
+			t.Error("Unexpected value", plugin)
+		}
+	}
+}
+
+func Test_Decoder_008(t *testing.T) {
+	// Optional attributes which are pointers are nil when not set
+	path := filepath.Join(t.TempDir(), "main.hcl")
+	if err := os.WriteFile(path, []byte(`
+nginx-gw "a" {
+    reload = false
+}
+
+nginx-gw "b" {
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := NewDecoder()
+	decoder.MustRegister(nginxgw.Config{})
+	plugins, err := decoder.Parse(os.DirFS("/"), path)
+	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
+		t.Fatal(err)
+	} else if len(plugins) != 2 {
+		t.Fatal("Unexpected number of plugins")
+	}
+	for _, plugin := range plugins {
+		plugin := plugin.(*nginxgw.Config)
+		if plugin.Label() == "a" && (plugin.Reload == nil || *plugin.Reload) {
+			t.Error("Unexpected value", plugin)
+		}
+		if plugin.Label() == "b" && plugin.Reload != nil {
+			t.Error("Unexpected value", plugin)
+		}
+	}
+}
+
+func Test_Decoder_009(t *testing.T) {
+	// Encoding functions
+	path := filepath.Join(t.TempDir(), "main.hcl")
+	if err := os.WriteFile(path, []byte(`
+httpserver "main" {
+    listen = base64decode(base64encode("localhost:8080"))
+}
+
+tokenauth "main" {
+    path = urlencode("/var/lib/a b?c=d")
+    file = base64encode("tokens.json")
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := NewDecoder()
+	decoder.MustRegister(httpserver.Config{})
+	decoder.MustRegister(tokenauth.Config{})
+	plugins, err := decoder.Parse(os.DirFS("/"), path)
+	if err != nil {
+		decoder.WriteDiagnostics(os.Stderr, err)
+		t.Fatal(err)
+	} else if len(plugins) != 2 {
+		t.Fatal("Unexpected number of plugins")
+	}
+	if plugin := plugins[0].(*httpserver.Config); plugin.Addr != "localhost:8080" {
+		t.Error("Unexpected value", plugin)
+	}
+	if plugin := plugins[1].(*tokenauth.Config); plugin.Path != "%2Fvar%2Flib%2Fa+b%3Fc%3Dd" || plugin.File != "dG9rZW5zLmpzb24=" {
+		t.Error("Unexpected value", plugin)
+	}
+
+	// Invalid base64 data
+	if err := os.WriteFile(path, []byte(`
+httpserver "main" {
+    listen = base64decode("not base64!")
+}
+`), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err := decoder.Parse(os.DirFS("/"), path); err == nil {
+		t.Error("Expected error for invalid base64 data")
+	} else {
+		t.Log(err)
+	}
 }
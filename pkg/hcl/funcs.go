@@ -0,0 +1,291 @@
+package hcl
+
+import (
+	"encoding/base64"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	// Modules
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"github.com/zclconf/go-cty/cty/gocty"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Functions from the go-cty standard library
+var stdfns = map[string]function.Function{
+	// String functions
+	"chomp":        stdlib.ChompFunc,
+	"format":       stdlib.FormatFunc,
+	"formatlist":   stdlib.FormatListFunc,
+	"indent":       stdlib.IndentFunc,
+	"join":         stdlib.JoinFunc,
+	"lower":        stdlib.LowerFunc,
+	"regex":        stdlib.RegexFunc,
+	"regexall":     stdlib.RegexAllFunc,
+	"regexreplace": stdlib.RegexReplaceFunc,
+	"replace":      stdlib.ReplaceFunc,
+	"split":        stdlib.SplitFunc,
+	"strlen":       stdlib.StrlenFunc,
+	"strrev":       stdlib.ReverseFunc,
+	"substr":       stdlib.SubstrFunc,
+	"title":        stdlib.TitleFunc,
+	"trim":         stdlib.TrimFunc,
+	"trimprefix":   stdlib.TrimPrefixFunc,
+	"trimspace":    stdlib.TrimSpaceFunc,
+	"trimsuffix":   stdlib.TrimSuffixFunc,
+	"upper":        stdlib.UpperFunc,
+
+	// Collection functions
+	"chunklist":    stdlib.ChunklistFunc,
+	"coalesce":     stdlib.CoalesceFunc,
+	"coalescelist": stdlib.CoalesceListFunc,
+	"compact":      stdlib.CompactFunc,
+	"concat":       stdlib.ConcatFunc,
+	"contains":     stdlib.ContainsFunc,
+	"distinct":     stdlib.DistinctFunc,
+	"element":      stdlib.ElementFunc,
+	"flatten":      stdlib.FlattenFunc,
+	"index":        stdlib.IndexFunc,
+	"keys":         stdlib.KeysFunc,
+	"length":       stdlib.LengthFunc,
+	"lookup":       stdlib.LookupFunc,
+	"merge":        stdlib.MergeFunc,
+	"range":        stdlib.RangeFunc,
+	"reverse":      stdlib.ReverseListFunc,
+	"setunion":     stdlib.SetUnionFunc,
+	"slice":        stdlib.SliceFunc,
+	"sort":         stdlib.SortFunc,
+	"values":       stdlib.ValuesFunc,
+	"zipmap":       stdlib.ZipmapFunc,
+
+	// Numeric functions
+	"abs":   stdlib.AbsoluteFunc,
+	"ceil":  stdlib.CeilFunc,
+	"floor": stdlib.FloorFunc,
+	"max":   stdlib.MaxFunc,
+	"min":   stdlib.MinFunc,
+
+	// Encoding functions
+	"csvdecode":  stdlib.CSVDecodeFunc,
+	"jsondecode": stdlib.JSONDecodeFunc,
+	"jsonencode": stdlib.JSONEncodeFunc,
+
+	// Conversion functions
+	"tobool":   stdlib.MakeToFunc(cty.Bool),
+	"tonumber": stdlib.MakeToFunc(cty.Number),
+	"tostring": stdlib.MakeToFunc(cty.String),
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// setFunctions sets the functions for decoding, where relative paths for
+// files are resolved relative to the base path
+func (d *decoder) setFunctions(filesys fs.FS, base string) {
+	d.filesys = filesys
+	for name, fn := range stdfns {
+		d.fns[name] = fn
+	}
+	d.fns["env"] = envFunc
+	d.fns["duration"] = durationFunc
+	d.fns["base64encode"] = base64EncodeFunc
+	d.fns["base64decode"] = base64DecodeFunc
+	d.fns["urlencode"] = urlEncodeFunc
+	d.fns["file"] = fileFunc(filesys, base)
+	d.fns["templatefile"] = templateFileFunc(filesys, base, d.fns)
+}
+
+// context returns an evaluation context for a file, where relative paths
+// for files are resolved relative to the directory of the file. Returns
+// the parent context if the file is not known
+func (d *decoder) context(parent *hcl.EvalContext, filename string) *hcl.EvalContext {
+	if filename == "" || d.filesys == nil {
+		return parent
+	}
+	base := filepath.Join(string(os.PathSeparator), filepath.Dir(filename))
+	ctx := parent.NewChild()
+	ctx.Functions = map[string]function.Function{
+		"file":         fileFunc(d.filesys, base),
+		"templatefile": templateFileFunc(d.filesys, base, d.fns),
+	}
+	return ctx
+}
+
+// envFunc returns the value of an environment variable, or an empty string
+// if the environment variable is not set
+var envFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "name", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(os.Getenv(args[0].AsString())), nil
+	},
+})
+
+// durationFunc checks a duration such as "1h30m" and returns it in
+// canonical form. A number is interpreted as seconds
+var durationFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "duration", Type: cty.DynamicPseudoType},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		switch args[0].Type() {
+		case cty.String:
+			if d, err := time.ParseDuration(args[0].AsString()); err != nil {
+				return cty.NilVal, function.NewArgErrorf(0, "invalid duration %q", args[0].AsString())
+			} else {
+				return cty.StringVal(d.String()), nil
+			}
+		case cty.Number:
+			var secs float64
+			if err := gocty.FromCtyValue(args[0], &secs); err != nil {
+				return cty.NilVal, function.NewArgError(0, err)
+			}
+			return cty.StringVal(time.Duration(secs * float64(time.Second)).String()), nil
+		default:
+			return cty.NilVal, function.NewArgErrorf(0, "string or number required")
+		}
+	},
+})
+
+// base64EncodeFunc returns the base64 encoding of a string
+var base64EncodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(args[0].AsString()))), nil
+	},
+})
+
+// base64DecodeFunc decodes a base64 string, which must decode to valid UTF-8
+var base64DecodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		if data, err := base64.StdEncoding.DecodeString(args[0].AsString()); err != nil {
+			return cty.NilVal, function.NewArgErrorf(0, "invalid base64 data")
+		} else if !utf8.Valid(data) {
+			return cty.NilVal, function.NewArgErrorf(0, "decoded data is not valid UTF-8")
+		} else {
+			return cty.StringVal(string(data)), nil
+		}
+	},
+})
+
+// urlEncodeFunc escapes a string so it can be used in a URL query
+var urlEncodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(url.QueryEscape(args[0].AsString())), nil
+	},
+})
+
+// fileFunc returns a function which reads the contents of a file
+func fileFunc(filesys fs.FS, base string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			if data, err := readfile(filesys, base, args[0].AsString()); err != nil {
+				return cty.NilVal, function.NewArgError(0, err)
+			} else {
+				return cty.StringVal(string(data)), nil
+			}
+		},
+	})
+}
+
+// templateFileFunc returns a function which reads a file and renders
+// it as a template with variables. The functions, except templatefile,
+// can be used within the template, where relative paths for files are
+// resolved relative to the directory of the template
+func templateFileFunc(filesys fs.FS, base string, fns map[string]function.Function) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "vars", Type: cty.DynamicPseudoType},
+		},
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			path := resolve(base, args[0].AsString())
+			data, err := readfile(filesys, base, path)
+			if err != nil {
+				return cty.NilVal, function.NewArgError(0, err)
+			}
+			expr, diags := hclsyntax.ParseTemplate(data, path, hcl.InitialPos)
+			if diags.HasErrors() {
+				return cty.NilVal, function.NewArgError(0, diags)
+			}
+
+			// Set variables and functions
+			ctx := &hcl.EvalContext{
+				Variables: make(map[string]cty.Value),
+				Functions: make(map[string]function.Function, len(fns)),
+			}
+			if vars := args[1]; !vars.IsNull() {
+				if !vars.CanIterateElements() || !(vars.Type().IsObjectType() || vars.Type().IsMapType()) {
+					return cty.NilVal, function.NewArgErrorf(1, "object or map required")
+				}
+				for key, value := range vars.AsValueMap() {
+					ctx.Variables[key] = value
+				}
+			}
+			for name, fn := range fns {
+				if name != "templatefile" {
+					ctx.Functions[name] = fn
+				}
+			}
+			ctx.Functions["file"] = fileFunc(filesys, filepath.Dir(path))
+
+			// Render the template
+			if value, diags := expr.Value(ctx); diags.HasErrors() {
+				return cty.NilVal, diags
+			} else {
+				return value, nil
+			}
+		},
+	})
+}
+
+// resolve returns a path, where relative paths are resolved against the base
+func resolve(base, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return path
+}
+
+// readfile reads a file, where relative paths are resolved against the base
+func readfile(filesys fs.FS, base, path string) ([]byte, error) {
+	path = resolve(base, path)
+	if data, err := fs.ReadFile(filesys, strings.TrimPrefix(filepath.Clean(path), string(os.PathSeparator))); err != nil {
+		return nil, ErrNotFound.Withf("%q", path)
+	} else {
+		return data, nil
+	}
+}
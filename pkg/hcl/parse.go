@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	// Module imports
@@ -20,40 +21,63 @@ import (
 const (
 	fileExtHCL  = ".hcl"
 	fileExtJSON = ".json"
+	fileExtVars = ".vars.hcl"
 )
 
 /////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
-func Parse(filesys fs.FS, path string) (hcl.Body, error) {
+// Parse files and folders, and return the merged body. Variable files
+// are not included in the body
+func Parse(filesys fs.FS, paths ...string) (hcl.Body, error) {
+	body, _, _, err := parse(filesys, paths...)
+	return hcl.MergeFiles(body), err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parse returns the files which are not variable files in path order, the
+// merged body of the variable files and the parsed files, keyed by path
+func parse(filesys fs.FS, paths ...string) ([]*hcl.File, hcl.Body, map[string]*hcl.File, error) {
 	parser := hclparse.NewParser()
-	if err := fs.WalkDir(filesys, strings.TrimPrefix(path, string(os.PathSeparator)), func(path string, info fs.DirEntry, err error) error {
-		return walkconfig(parser, filesys, path, info, err)
-	}); err != nil {
-		return nil, err
+	for _, path := range paths {
+		root := strings.TrimPrefix(path, string(os.PathSeparator))
+		if err := fs.WalkDir(filesys, root, func(path string, info fs.DirEntry, err error) error {
+			return walkconfig(parser, filesys, root, path, info, err)
+		}); err != nil {
+			return nil, nil, parser.Files(), err
+		}
 	}
 
-	// Merge files together
+	// Order files by path, and merge the variable files together
 	files := parser.Files()
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 	body := make([]*hcl.File, 0, len(files))
-	for _, file := range files {
-		body = append(body, file)
+	vars := make([]*hcl.File, 0, len(files))
+	for _, key := range keys {
+		if isVarsFile(key) {
+			vars = append(vars, files[key])
+		} else {
+			body = append(body, files[key])
+		}
 	}
 
 	// Return success
-	return hcl.MergeFiles(body), nil
+	return body, hcl.MergeFiles(vars), files, nil
 }
 
-///////////////////////////////////////////////////////////////////////////////
-// PRIVATE METHODS
-
-func walkconfig(parser *hclparse.Parser, filesys fs.FS, path string, d fs.DirEntry, err error) error {
+func walkconfig(parser *hclparse.Parser, filesys fs.FS, root, path string, d fs.DirEntry, err error) error {
 	// Pass down any error
 	if err != nil {
 		return err
 	}
 	// Ignore any hidden files
-	if strings.HasPrefix(d.Name(), ".") {
+	if strings.HasPrefix(d.Name(), ".") && path != root {
 		if d.IsDir() {
 			return fs.SkipDir
 		} else {
@@ -83,11 +107,19 @@ func walkconfig(parser *hclparse.Parser, filesys fs.FS, path string, d fs.DirEnt
 			return diags
 		}
 	default:
-		return ErrNotImplemented.Withf("%q", d.Name())
+		// Other files within folders are ignored
+		if path == root {
+			return ErrNotImplemented.Withf("%q", d.Name())
+		}
 	}
 	return nil
 }
 
+// isVarsFile returns true if the path is a variable file
+func isVarsFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), fileExtVars)
+}
+
 // Read all bytes from regular file
 func readall(filesys fs.FS, path string) ([]byte, error) {
 	return fs.ReadFile(filesys, path)
@@ -6,9 +6,13 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/zclconf/go-cty/cty"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
 
-	// Namespace imports
-	. "github.com/djthorpe/go-errors"
+var (
+	varName = varblock{}.Name()
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -42,32 +46,42 @@ func (r *refs) String() string {
 // PUBLIC METHODS
 
 func (r *refs) CreateReferences(body hcl.Body, spec hcldec.Spec) error {
-	varName := varblock{}.Name() // "var"
-	traversal := hcldec.Variables(body, spec)
-	for _, t := range traversal {
+	var result hcl.Diagnostics
+	for _, t := range hcldec.Variables(body, spec) {
 		ts := t.SimpleSplit()
-		refName := ts.RootName()
-		if len(ts.Rel) != 1 {
-			return ErrBadParameter.Withf("invalid reference: %q", t.SourceRange())
+		if len(ts.Rel) < 1 {
+			result = append(result, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid reference",
+				Detail:   fmt.Sprintf("A reference to %q requires a label", ts.RootName()),
+				Subject:  t.SourceRange().Ptr(),
+			})
+			continue
 		}
-		refIdentifier := ts.Rel[0].(hcl.TraverseAttr).Name
-
-		if refName == varName {
-			// 'var' references are stored at the top level
-			r.references[refIdentifier] = nil
-		} else {
-			r.references[refName] = append(r.references[refName], refIdentifier)
+		refName := ts.RootName()
+		if attr, ok := ts.Rel[0].(hcl.TraverseAttr); ok {
+			r.references[refName] = append(r.references[refName], attr.Name)
 		}
 	}
+	if result.HasErrors() {
+		return result
+	}
 	return nil
 }
 
-func (r *refs) Context() *hcl.EvalContext {
+// Context returns an evaluation context where each variable is set from vars,
+// and each reference to a block evaluates to the "name.label" of the block,
+// which is resolved into a task when the block is instantiated
+func (r *refs) Context(vars map[string]cty.Value) *hcl.EvalContext {
 	ctx := &hcl.EvalContext{
 		Variables: make(map[string]cty.Value),
 	}
 	for k := range r.references {
-		ctx.Variables[k] = r.objectValForName(k)
+		if k == varName {
+			ctx.Variables[k] = cty.ObjectVal(vars)
+		} else {
+			ctx.Variables[k] = r.objectValForName(k)
+		}
 	}
 	return ctx
 }
@@ -76,13 +90,9 @@ func (r *refs) Context() *hcl.EvalContext {
 // PRIVATE METHODS
 
 func (r *refs) objectValForName(name string) cty.Value {
-	values := r.references[name]
-	if values == nil {
-		return cty.DynamicVal.Mark(name)
-	}
 	result := make(map[string]cty.Value)
-	for _, label := range values {
-		result[label] = cty.DynamicVal.Mark(label)
+	for _, label := range r.references[name] {
+		result[label] = cty.StringVal(name + "." + label)
 	}
 	return cty.ObjectVal(result)
 }
@@ -3,8 +3,18 @@ package hcl
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
+
+	// Modules
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 
 	// Namespace imports
+	. "github.com/djthorpe/go-errors"
 	. "github.com/mutablelogic/terraform-provider-nginx"
 )
 
@@ -12,12 +22,23 @@ import (
 // TYPES
 
 type varblock struct {
-	Label       string `hcl:"label,label"`
-	Type        string `hcl:"type"`
-	Default     any    `hcl:"default,optional"`
-	Description string `hcl:"description,optional"`
+	Label_      string    `hcl:"label,label"`
+	Type        string    `hcl:"type"`
+	Default     cty.Value `hcl:"default,optional"`
+	Description string    `hcl:"description,optional"`
+
+	// Source range of the block
+	rng hcl.Range
 }
 
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// Prefix for environment variables which set variable values
+	EnvVarPrefix = "NGINXGW_VAR_"
+)
+
 /////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -25,29 +46,187 @@ func (varblock) Name() string {
 	return "var"
 }
 
+func (v varblock) Label() string {
+	return v.Label_
+}
+
 func (v varblock) New(context.Context, Provider) (Task, error) {
 	return nil, nil
 }
 
+// SetVar sets a variable value from a string, which takes precedence over
+// the default and environment values, but not over values in variable files.
+// Where the variable type is not a string, the value is parsed as an
+// expression
+func (d *decoder) SetVar(name, value string) error {
+	if !hclsyntax.ValidIdentifier(name) {
+		return ErrBadParameter.Withf("invalid variable name %q", name)
+	}
+	d.setvars[name] = value
+	return nil
+}
+
 /////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
 func (v *varblock) String() string {
 	str := "<var"
-	if v.Label != "" {
-		str += fmt.Sprintf(" label=%q", v.Label)
+	if v.Label_ != "" {
+		str += fmt.Sprintf(" label=%q", v.Label_)
 	}
 	if v.Type != "" {
 		str += fmt.Sprintf(" type=%q", v.Type)
 	}
-	switch v.Default.(type) {
-	case string:
-		str += fmt.Sprintf(" default=%q", v.Default)
-	default:
-		str += fmt.Sprintf(" default=%v", v.Default)
+	if !v.Default.IsNull() {
+		str += fmt.Sprintf(" default=%v", v.Default.GoString())
 	}
 	if v.Description != "" {
 		str += fmt.Sprintf(" description=%q", v.Description)
 	}
 	return str + ">"
 }
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// bindVars sets the value for each variable from, in increasing order of
+// precedence: the default, the environment, SetVar and variable files
+func (d *decoder) bindVars(blocks []*varblock, files hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	// Obtain values from variable files
+	attrs, diags := files.JustAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	// Check for undeclared variables
+	declared := make(map[string]*varblock, len(blocks))
+	for _, v := range blocks {
+		if _, exists := declared[v.Label()]; exists {
+			diags = append(diags, v.NewDiagnostic("Duplicate variable", fmt.Sprintf("Variable %q is already defined", v.Label())))
+		}
+		declared[v.Label()] = v
+	}
+	for _, name := range sortedKeys(d.setvars) {
+		if _, exists := declared[name]; !exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Undeclared variable",
+				Detail:   fmt.Sprintf("A value was set for variable %q which has not been declared", name),
+			})
+		}
+	}
+	for name, attr := range attrs {
+		if _, exists := declared[name]; !exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Undeclared variable",
+				Detail:   fmt.Sprintf("A value was set for variable %q which has not been declared", name),
+				Subject:  attr.NameRange.Ptr(),
+			})
+		}
+	}
+
+	// Bind values to the variables
+	for _, v := range blocks {
+		ty, err := v.ctyType()
+		if err != nil {
+			diags = append(diags, v.NewDiagnostic("Invalid variable type", err.Error()))
+			continue
+		}
+
+		// Obtain the value with the highest precedence
+		var value cty.Value
+		var source string
+		if attr, exists := attrs[v.Label()]; exists {
+			var valueDiags hcl.Diagnostics
+			if value, valueDiags = attr.Expr.Value(d.context(&hcl.EvalContext{Functions: d.fns}, attr.Range.Filename)); valueDiags.HasErrors() {
+				diags = append(diags, valueDiags...)
+				continue
+			}
+			source = "variable file"
+		} else if str, exists := d.setvars[v.Label()]; exists {
+			value, err = valueFromString(ty, str)
+			source = "command line"
+		} else if str, exists := os.LookupEnv(EnvVarPrefix + v.Label()); exists {
+			value, err = valueFromString(ty, str)
+			source = "environment variable " + EnvVarPrefix + v.Label()
+		} else {
+			value = v.Default
+			source = "default"
+		}
+		if err != nil {
+			diags = append(diags, v.NewDiagnostic("Invalid value for variable", fmt.Sprintf("Variable %q from %s: %v", v.Label(), source, err)))
+			continue
+		} else if value.IsNull() {
+			diags = append(diags, v.NewDiagnostic("Missing variable", fmt.Sprintf("Variable %q is required but no value has been set", v.Label())))
+			continue
+		}
+
+		// Check the type
+		if value_, err := convert.Convert(value, ty); err != nil {
+			diags = append(diags, v.NewDiagnostic("Invalid value for variable", fmt.Sprintf("Variable %q from %s: %v", v.Label(), source, err)))
+		} else {
+			d.vars[v.Label()] = value_
+		}
+	}
+
+	// Return any errors
+	return diags
+}
+
+// NewDiagnostic returns an error diagnostic for the variable
+func (v *varblock) NewDiagnostic(summary, detail string) *hcl.Diagnostic {
+	diag := &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  summary,
+		Detail:   detail,
+	}
+	if v.rng.Filename != "" {
+		diag.Subject = v.rng.Ptr()
+	}
+	return diag
+}
+
+// ctyType returns the type constraint for the variable, such as "string",
+// "number", "bool", "list(string)" or "any"
+func (v *varblock) ctyType() (cty.Type, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(v.Type), v.rng.Filename, v.rng.Start)
+	if diags.HasErrors() {
+		return cty.NilType, diags
+	}
+	ty, diags := typeexpr.TypeConstraint(expr)
+	if diags.HasErrors() {
+		return cty.NilType, diags
+	}
+	return ty, nil
+}
+
+// valueFromString returns a value from a string. Where the type is a string
+// or any type, the value is used as-is, otherwise the value is parsed as an
+// expression
+func valueFromString(ty cty.Type, str string) (cty.Value, error) {
+	if ty == cty.String || ty == cty.DynamicPseudoType {
+		return cty.StringVal(str), nil
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(str), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	return value, nil
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -20,9 +20,10 @@ import (
 type Config struct {
 	Label_  string         `hcl:"label,label" json:"label"`
 	Router  types.Task     `hcl:"router,optional" json:"router"`
-	Addr    string         `hcl:"listen,optional" json:"listen"`   // Address or path for binding HTTP server
-	TLS     *TLS           `hcl:"tls,block" json:"tls"`            // TLS parameters
-	Timeout types.Duration `hcl:"timeout,optional" json:"timeout"` // Read timeout on HTTP requests
+	Addr    string         `hcl:"listen,optional" json:"listen"`        // Address or path for binding HTTP server
+	TLS     *TLS           `hcl:"tls,block" json:"tls"`                 // TLS parameters
+	Timeout types.Duration `hcl:"timeout,optional" json:"timeout"`      // Read timeout on HTTP requests
+	Policy  *types.Policy  `hcl:"policy,block" json:"policy,omitempty"` // Supervision policy for the task
 }
 
 type TLS struct {
@@ -66,7 +67,7 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 	// Create a router if it's not provided
 	if c.Router.Task == nil {
 		if router, err := provider.New(ctx, router.Config{
-			Label_: c.Label() + "-router",
+			L: c.Label() + "-router",
 		}); err != nil {
 			return nil, err
 		} else {
@@ -8,7 +8,7 @@ import (
 	"time"
 
 	// Modules
-
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 	fcgi "github.com/mutablelogic/terraform-provider-nginx/pkg/fcgi"
 
 	// Namespace imports
@@ -17,6 +17,7 @@ import (
 )
 
 type httpserver struct {
+	event.PubSub
 	Router
 	srv  *http.Server
 	fcgi *fcgi.Server
@@ -27,10 +27,16 @@ func (r *httpserver) Run(ctx context.Context) error {
 	if err := r.runInForeground(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		result = multierror.Append(result, err)
 	}
+	r.Emit(nil)
 	return result
 }
 
-// Return event channel. No events are sent by the httpserver
-func (*httpserver) C() <-chan Event {
-	return nil
+// Return event channel for the server, rather than the router
+func (r *httpserver) Sub() <-chan Event {
+	return r.PubSub.Sub()
+}
+
+// Unsubscribe from the event channel
+func (r *httpserver) Unsub(ch <-chan Event) {
+	r.PubSub.Unsub(ch)
 }
@@ -2,27 +2,39 @@ package logger
 
 import (
 	"context"
+	"strings"
 
 	// Modules
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
 	. "github.com/djthorpe/go-errors"
 	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
 )
 
 /////////////////////////////////////////////////////////////////////
 // TYPES
 
 type Config struct {
-	Label_ string `hcl:"label,label" json:"label,omitempty"` // Label for the configuration
+	Label_   string        `hcl:"label,label" json:"label,omitempty"`            // Label for the configuration
+	Level    string        `hcl:"level,optional" json:"level,omitempty"`         // Minimum level of messages (debug, info, warn, error)
+	Format   string        `hcl:"format,optional" json:"format,omitempty"`       // Output format (logfmt, json)
+	Path     string        `hcl:"path,optional" json:"path,omitempty"`           // Path to the log file, or empty for stderr
+	MaxSize  int64         `hcl:"max_size,optional" json:"max_size,omitempty"`   // Size in bytes at which the log file is rotated, or zero
+	MaxFiles uint          `hcl:"max_files,optional" json:"max_files,omitempty"` // Number of rotated log files to keep
+	Policy   *types.Policy `hcl:"policy,block" json:"policy,omitempty"`          // Supervision policy for the task
 }
 
 /////////////////////////////////////////////////////////////////////
 // GLOBALS
 
 const (
-	DefaultLabel = "logger"
+	DefaultLabel    = "logger"
+	DefaultMaxFiles = 5
+	FormatLogfmt    = "logfmt"
+	FormatJSON      = "json"
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -47,6 +59,47 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 		return nil, ErrBadParameter.Withf("label: %q", c.Label())
 	}
 
+	// Set configuration defaults
+	if c.Format == "" {
+		c.Format = FormatLogfmt
+	}
+	if c.MaxFiles == 0 {
+		c.MaxFiles = DefaultMaxFiles
+	}
+
+	// Check parameters
+	if _, err := ParseLevel(c.Level); err != nil {
+		return nil, err
+	}
+	if c.Format != FormatLogfmt && c.Format != FormatJSON {
+		return nil, ErrBadParameter.Withf("format: %q", c.Format)
+	}
+	if c.MaxSize < 0 {
+		return nil, ErrBadParameter.Withf("max_size: %v", c.MaxSize)
+	}
+
 	// Return configuration
 	return NewWithConfig(c)
 }
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ParseLevel returns a log level from a string, where an empty string
+// is the info level
+func ParseLevel(v string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "":
+		return LogInfo, nil
+	case LogDebug.String():
+		return LogDebug, nil
+	case LogInfo.String():
+		return LogInfo, nil
+	case LogWarn.String(), "warning":
+		return LogWarn, nil
+	case LogError.String():
+		return LogError, nil
+	default:
+		return LogInfo, ErrBadParameter.Withf("level: %q", v)
+	}
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// file is a log file which is rotated when it reaches a maximum size.
+// Rotated files have a numeric suffix, where the most recent is ".1"
+type file struct {
+	sync.Mutex
+
+	path     string
+	maxsize  int64
+	maxfiles uint
+	fh       *os.File
+	size     int64
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	fileFlags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	fileMode  = 0644
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// openFile opens a log file for appending, which is rotated when it
+// reaches maxsize bytes, or never rotated if maxsize is zero
+func openFile(path string, maxsize int64, maxfiles uint) (*file, error) {
+	f := &file{path: path, maxsize: maxsize, maxfiles: maxfiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close the log file, which is opened again on the next write
+func (f *file) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.fh == nil {
+		return nil
+	}
+	err := f.fh.Close()
+	f.fh = nil
+	return err
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f *file) String() string {
+	str := "<file"
+	str += fmt.Sprintf(" path=%q", f.path)
+	if f.maxsize > 0 {
+		str += fmt.Sprintf(" max_size=%v max_files=%v", f.maxsize, f.maxfiles)
+	}
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write data to the log file, rotating the file first if the data would
+// exceed the maximum size. The file is opened if it has been closed, or
+// could not be opened when it was last rotated
+func (f *file) Write(data []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.fh == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.maxsize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxsize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.fh.Write(data)
+	f.size += int64(n)
+	return n, err
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// open the log file and set the current size
+func (f *file) open() error {
+	fh, err := os.OpenFile(f.path, fileFlags, fileMode)
+	if err != nil {
+		return err
+	}
+	info, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		return err
+	}
+	f.fh = fh
+	f.size = info.Size()
+	return nil
+}
+
+// rotate closes the log file, renames the existing files and then opens
+// a new log file. The oldest file is removed. If the files cannot be
+// renamed, the log file is opened again on the next write
+func (f *file) rotate() error {
+	err := f.fh.Close()
+	f.fh = nil
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(f.rotated(f.maxfiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := f.maxfiles; i > 1; i-- {
+		if err := os.Rename(f.rotated(i-1), f.rotated(i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if f.maxfiles > 0 {
+		if err := os.Rename(f.path, f.rotated(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// rotated returns the path for a rotated file
+func (f *file) rotated(n uint) string {
+	return fmt.Sprint(f.path, ".", n)
+}
@@ -2,13 +2,16 @@ package logger
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
 
 	// Modules
-	"github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	taskcontext "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
 
 	// Namespace imports
-	//. "github.com/djthorpe/go-errors"
 	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
 )
 
@@ -17,13 +20,48 @@ import (
 
 type logger struct {
 	provider.Task
+
+	level  LogLevel
+	format string
+	file   *file
+	log    *slog.Logger
 }
 
 /////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
 func NewWithConfig(c Config) (Logger, error) {
-	return new(logger), nil
+	r := new(logger)
+	r.format = c.Format
+
+	// Set the level
+	if level, err := ParseLevel(c.Level); err != nil {
+		return nil, err
+	} else {
+		r.level = level
+	}
+
+	// Write to stderr, or a file which is rotated
+	var w io.Writer = os.Stderr
+	if c.Path != "" {
+		if file, err := openFile(c.Path, c.MaxSize, c.MaxFiles); err != nil {
+			return nil, err
+		} else {
+			r.file = file
+			w = file
+		}
+	}
+
+	// Create the handler for the format
+	opts := &slog.HandlerOptions{Level: slog.Level(r.level)}
+	if c.Format == FormatJSON {
+		r.log = slog.New(slog.NewJSONHandler(w, opts))
+	} else {
+		r.log = slog.New(slog.NewTextHandler(w, opts))
+	}
+
+	// Return success
+	return r, nil
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -31,18 +69,79 @@ func NewWithConfig(c Config) (Logger, error) {
 
 func (r *logger) String() string {
 	str := "<logger"
+	str += fmt.Sprintf(" level=%v", r.level)
+	str += fmt.Sprintf(" format=%q", r.format)
+	if r.file != nil {
+		str += fmt.Sprint(" file=", r.file)
+	}
 	return str + ">"
 }
 
 /////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
-// Log a message with arguments
-func (r *logger) Log(_ context.Context, args ...any) {
-	log.Print(args...)
+// Run until done, and then close the log file. The file is opened again
+// when the logger is restarted and written to
+func (r *logger) Run(ctx context.Context) error {
+	err := r.Task.Run(ctx)
+	if r.file != nil {
+		if err_ := r.file.Close(); err_ != nil && err == nil {
+			err = err_
+		}
+	}
+	return err
+}
+
+// Log a message with arguments at info level
+func (r *logger) Log(ctx context.Context, args ...any) {
+	r.print(ctx, LogInfo, fmt.Sprint(args...))
+}
+
+// Log a message with formatted arguments at info level
+func (r *logger) Logf(ctx context.Context, format string, args ...any) {
+	r.print(ctx, LogInfo, fmt.Sprintf(format, args...))
+}
+
+// Log a message with arguments at a level
+func (r *logger) Print(ctx context.Context, level LogLevel, args ...any) {
+	if level >= r.level {
+		r.print(ctx, level, fmt.Sprint(args...))
+	}
+}
+
+// Log a message with formatted arguments at a level
+func (r *logger) Printf(ctx context.Context, level LogLevel, format string, args ...any) {
+	if level >= r.level {
+		r.print(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// print a message with fields from the context
+func (r *logger) print(ctx context.Context, level LogLevel, msg string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r.log.LogAttrs(ctx, slog.Level(level), msg, fields(ctx)...)
 }
 
-// Log a message with formatted arguments
-func (r *logger) Logf(_ context.Context, format string, args ...any) {
-	log.Printf(format, args...)
+// fields returns the task name and label, and the request prefix and
+// parameters from the context
+func fields(ctx context.Context) []slog.Attr {
+	var result []slog.Attr
+	if name := taskcontext.Name(ctx); name != "" {
+		result = append(result, slog.String("name", name))
+	}
+	if label := taskcontext.Label(ctx); label != "" {
+		result = append(result, slog.String("label", label))
+	}
+	if prefix := taskcontext.Prefix(ctx); prefix != "" {
+		result = append(result, slog.String("prefix", prefix))
+	}
+	if params := taskcontext.Params(ctx); len(params) > 0 {
+		result = append(result, slog.Any("params", params))
+	}
+	return result
 }
@@ -0,0 +1,156 @@
+package logger_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Module imports
+	taskcontext "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx/pkg/logger"
+)
+
+func Test_Logger_001(t *testing.T) {
+	// Check levels are parsed and invalid configurations rejected
+	if level, err := ParseLevel("WARN"); err != nil {
+		t.Fatal(err)
+	} else if level != plugin.LogWarn {
+		t.Error("Unexpected level", level)
+	}
+	if _, err := (Config{Level: "verbose"}).New(context.Background(), nil); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+	if _, err := (Config{Format: "xml"}).New(context.Background(), nil); err == nil {
+		t.Error("Expected error for invalid format")
+	}
+}
+
+func Test_Logger_002(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	task, err := Config{Level: "info", Format: "json", Path: path}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := task.(plugin.Logger)
+	t.Log(logger)
+
+	// Log messages with fields from the context, debug message is filtered
+	ctx, cancel := context.WithCancel(taskcontext.WithNameLabel(context.Background(), "name", "label"))
+	logger.Print(ctx, plugin.LogDebug, "debug")
+	logger.Logf(ctx, "hello %v", "world")
+	logger.Print(ctx, plugin.LogError, "error")
+	cancel()
+	if err := task.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		t.Error(err)
+	}
+
+	// Check the output
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected two lines, got %q", lines)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["msg"] != "hello world" || record["level"] != "INFO" || record["name"] != "name" || record["label"] != "label" {
+		t.Error("Unexpected record", record)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	} else if record["level"] != "ERROR" {
+		t.Error("Unexpected record", record)
+	}
+}
+
+func Test_Logger_003(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	task, err := Config{Path: path, MaxSize: 100, MaxFiles: 2}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := task.(plugin.Logger)
+
+	// Write enough messages to rotate the file more than twice
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 20; i++ {
+		logger.Log(ctx, "message ", i)
+	}
+	cancel()
+	if err := task.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		t.Error(err)
+	}
+
+	// Check the rotated files
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if info, err := os.Stat(name); err != nil {
+			t.Error(err)
+		} else if info.Size() > 100 {
+			t.Error("Unexpected size", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected no third rotated file")
+	}
+}
+
+func Test_Logger_004(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	task, err := Config{Path: path}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := task.(plugin.Logger)
+
+	// Messages are written after the logger is restarted
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		logger.Log(ctx, "run ", i)
+		cancel()
+		if err := task.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			t.Error(err)
+		}
+	}
+	if data, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), "run 0") || !strings.Contains(string(data), "run 1") {
+		t.Errorf("Unexpected output %q", data)
+	}
+}
+
+func Test_Logger_005(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	task, err := Config{Path: path, MaxSize: 100, MaxFiles: 1}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := task.(plugin.Logger)
+
+	// Messages are written after the file could not be rotated
+	if err := os.MkdirAll(filepath.Join(path+".1", "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		logger.Log(context.Background(), "failed ", i)
+	}
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(context.Background(), "rotated")
+	if data, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), "rotated") {
+		t.Errorf("Unexpected output %q", data)
+	}
+}
@@ -18,10 +18,11 @@ import (
 // TYPES
 
 type Config struct {
-	L   string         `hcl:"label,label" json:"label,omitempty"`      // Label
-	D   string         `hcl:"domain,optional" json:"domain,omitempty"` // mDNS Domain
-	TTL types.Duration `hcl:"ttl,optional" json:"ttl,omitempty"`       // TTL
-	T   types.Task     `hcl:"mdns,optional" json:"mdns,omitempty"`     // mDNS task
+	L      string         `hcl:"label,label" json:"label,omitempty"`      // Label
+	D      string         `hcl:"domain,optional" json:"domain,omitempty"` // mDNS Domain
+	TTL    types.Duration `hcl:"ttl,optional" json:"ttl,omitempty"`       // TTL
+	T      types.Task     `hcl:"mdns,optional" json:"mdns,omitempty"`     // mDNS task
+	Policy *types.Policy  `hcl:"policy,block" json:"policy,omitempty"`    // Supervision policy for the task
 }
 
 ///////////////////////////////////////////////////////////////////////////////
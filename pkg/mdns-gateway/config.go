@@ -25,6 +25,7 @@ type Config struct {
 	Interface string         `hcl:"interface,optional" json:"interface,omitempty"` // Interface name
 	T         types.Duration `hcl:"ttl,optional" json:"ttl,omitempty"`             // TTL
 	Task      types.Task     `hcl:"discovery,optional" json:"discovery,omitempty"` // NetServicesTask (optional)
+	Policy    *types.Policy  `hcl:"policy,block" json:"policy,omitempty"`          // Supervision policy for the task
 }
 
 ///////////////////////////////////////////////////////////////////////////////
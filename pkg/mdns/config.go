@@ -6,6 +6,7 @@ import (
 	"time"
 
 	// Modules
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
@@ -17,8 +18,9 @@ import (
 // TYPES
 
 type Config struct {
-	L         string `hcl:"label,label" json:"label,omitempty"`            // Label for the configuration
-	Interface string `hcl:"interface,optional" json:"interface,omitempty"` // The interface to bind to. Defaults to all broadcast interfaces
+	L         string        `hcl:"label,label" json:"label,omitempty"`            // Label for the configuration
+	Interface string        `hcl:"interface,optional" json:"interface,omitempty"` // The interface to bind to. Defaults to all broadcast interfaces
+	Policy    *types.Policy `hcl:"policy,block" json:"policy,omitempty"`          // Supervision policy for the task
 
 	iface []net.Interface
 }
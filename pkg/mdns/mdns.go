@@ -128,8 +128,6 @@ FOR_LOOP:
 			if msg != nil {
 				if err := t.send(ip4, ip6, msg); err != nil {
 					t.Emit(event.NewError(err))
-				} else {
-					t.Emit(event.NewEvent(Send, msg))
 				}
 			}
 		}
@@ -184,6 +182,8 @@ func (t *mdns) interfaceWithIndex(ifIndex int) net.Interface {
 	}
 }
 
+// run4 receives packets on the IPv4 connection, and emits a Receive
+// event for each packet until the connection is closed
 func (t *mdns) run4(ctx context.Context, conn *ipv4.PacketConn) {
 	buf := make([]byte, 65536)
 	for {
@@ -197,13 +197,15 @@ func (t *mdns) run4(ctx context.Context, conn *ipv4.PacketConn) {
 				continue
 			} else if msg, err := MessageFromPacket(buf[:n], from, t.interfaceWithIndex(cm.IfIndex)); err != nil {
 				t.Emit(event.NewError(err))
-			} else if msg.IsAnswer() && len(msg.PTR()) > 0 {
+			} else {
 				t.Emit(event.NewEvent(Receive, msg))
 			}
 		}
 	}
 }
 
+// run6 receives packets on the IPv6 connection, and emits a Receive
+// event for each packet until the connection is closed
 func (t *mdns) run6(ctx context.Context, conn *ipv6.PacketConn) {
 	buf := make([]byte, 65536)
 	for {
@@ -217,14 +219,15 @@ func (t *mdns) run6(ctx context.Context, conn *ipv6.PacketConn) {
 				continue
 			} else if msg, err := MessageFromPacket(buf[:n], from, t.interfaceWithIndex(cm.IfIndex)); err != nil {
 				t.Emit(event.NewError(err))
-			} else if msg.IsAnswer() && len(msg.PTR()) > 0 {
+			} else {
 				t.Emit(event.NewEvent(Receive, msg))
 			}
 		}
 	}
 }
 
-// Send a single DNS message to a particular interface or all interfaces if 0
+// Send a single DNS message to a particular interface or all interfaces if 0,
+// and emit a Send event for each packet sent
 func (t *mdns) send(conn4 *ipv4.PacketConn, conn6 *ipv6.PacketConn, message Message) error {
 	var result error
 
@@ -242,12 +245,16 @@ func (t *mdns) send(conn4 *ipv4.PacketConn, conn6 *ipv6.PacketConn, message Mess
 			cm.IfIndex = ifIndex
 			if _, err := conn4.WriteTo(data, &cm, multicastAddrIp4); err != nil {
 				result = multierror.Append(result, err)
+			} else {
+				t.Emit(event.NewEvent(Send, message))
 			}
 		} else {
 			for _, intf := range t.ifaces {
 				cm.IfIndex = intf.Index
 				if _, err := conn4.WriteTo(data, &cm, multicastAddrIp4); err != nil {
 					result = multierror.Append(result, err)
+				} else {
+					t.Emit(event.NewEvent(Send, message))
 				}
 			}
 		}
@@ -260,12 +267,16 @@ func (t *mdns) send(conn4 *ipv4.PacketConn, conn6 *ipv6.PacketConn, message Mess
 			cm.IfIndex = ifIndex
 			if _, err := conn6.WriteTo(data, &cm, multicastAddrIp6); err != nil {
 				result = multierror.Append(result, err)
+			} else {
+				t.Emit(event.NewEvent(Send, message))
 			}
 		} else {
 			for _, intf := range t.ifaces {
 				cm.IfIndex = intf.Index
 				if _, err := conn6.WriteTo(data, &cm, multicastAddrIp6); err != nil {
 					result = multierror.Append(result, err)
+				} else {
+					t.Emit(event.NewEvent(Send, message))
 				}
 			}
 		}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+
+	// Module imports
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Config struct {
+	Label_     string        `hcl:"label,label" json:"label,omitempty"`
+	Prefix     string        `hcl:"prefix,optional" json:"prefix,omitempty"`
+	Middleware []string      `hcl:"middleware,optional" json:"middleware,omitempty"` // Middleware applied to handlers, in order
+	Router     types.Task    `hcl:"router,optional" json:"router"`                   // plugin.Router
+	Policy     *types.Policy `hcl:"policy,block" json:"policy,omitempty"`            // Supervision policy for the task
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DefaultName   = "metrics"
+	DefaultLabel  = DefaultName
+	DefaultPrefix = "/"
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
+	// Check arguments
+	if _, ok := c.Router.Task.(Router); c.Router.Task == nil || !ok {
+		return nil, ErrBadParameter.With("router")
+	}
+	if _, ok := provider.(TaskProvider); !ok {
+		return nil, ErrBadParameter.With("provider")
+	}
+
+	// Set configuration defaults
+	if c.Prefix == "" {
+		c.Prefix = DefaultPrefix
+	}
+
+	// Check parameters
+	if !util.IsIdentifier(c.Label()) {
+		return nil, ErrBadParameter.Withf("label: %q", c.Label())
+	}
+
+	// Return new task
+	return NewWithConfig(c, provider.(TaskProvider))
+}
+
+func (c Config) Name() string {
+	return DefaultName
+}
+
+func (c Config) Label() string {
+	if c.Label_ == "" {
+		return DefaultLabel
+	} else {
+		return c.Label_
+	}
+}
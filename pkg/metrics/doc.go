@@ -0,0 +1,8 @@
+// metrics plugin collects metrics from the events emitted by tasks, and
+// serves them in Prometheus text format on a router prefix. It collects:
+//
+//   - Request counts and latencies for each route of a router;
+//   - Token match and miss counts from tokenauth;
+//   - Create, revoke, enable, disable and reload outcomes from nginx;
+//   - Packets sent and received by mDNS.
+package metrics
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// counter is a family of counters, keyed by label values
+type counter struct {
+	name, help string
+	labels     []string
+	values     map[string]float64
+}
+
+// histogram is a family of histograms, keyed by label values
+type histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	values     map[string]*observations
+}
+
+// observations are the counts for each bucket of a histogram, and the
+// sum and count of all observations
+type observations struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// Default buckets for latencies, in seconds
+	defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// Escape label values
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func newCounter(name, help string, labels ...string) *counter {
+	return &counter{name, help, labels, make(map[string]float64)}
+}
+
+func newHistogram(name, help string, buckets []float64, labels ...string) *histogram {
+	return &histogram{name, help, labels, buckets, make(map[string]*observations)}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// inc increments a counter for label values
+func (c *counter) inc(values ...string) {
+	c.values[labelString(c.labels, values)]++
+}
+
+// observe adds an observation to a histogram for label values
+func (h *histogram) observe(v float64, values ...string) {
+	key := labelString(h.labels, values)
+	o, exists := h.values[key]
+	if !exists {
+		o = &observations{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = o
+	}
+	for i, bucket := range h.buckets {
+		if v <= bucket {
+			o.counts[i]++
+		}
+	}
+	o.sum += v
+	o.count++
+}
+
+// write the counter in Prometheus text format
+func (c *counter) write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(c.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, braces(key), formatFloat(c.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write the histogram in Prometheus text format
+func (h *histogram) write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(h.values) {
+		o := h.values[key]
+		for i, bucket := range h.buckets {
+			le := labelString([]string{"le"}, []string{formatFloat(bucket)})
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braces(key, le), o.counts[i]); err != nil {
+				return err
+			}
+		}
+		le := labelString([]string{"le"}, []string{"+Inf"})
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braces(key, le), o.count); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, braces(key), formatFloat(o.sum), h.name, braces(key), o.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// labelString returns label names and values as name="value" pairs,
+// separated by commas
+func labelString(names, values []string) string {
+	pairs := make([]string, 0, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+"=\""+labelEscaper.Replace(value)+"\"")
+	}
+	return strings.Join(pairs, ",")
+}
+
+// braces returns label strings within braces, or an empty string if
+// there are no labels
+func braces(labels ...string) string {
+	var result []string
+	for _, label := range labels {
+		if label != "" {
+			result = append(result, label)
+		}
+	}
+	if len(result) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(result, ",") + "}"
+}
+
+// formatFloat returns a value in Prometheus text format
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+
+	// Module imports
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type metrics struct {
+	event.PubSub
+	sync.Mutex
+
+	provider      TaskProvider
+	router        Router
+	label, prefix string
+	middleware    []string
+
+	requests *counter
+	latency  *histogram
+	tokens   *counter
+	nginx    *counter
+	packets  *counter
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	contentTypeMetrics = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	rePathMetrics = regexp.MustCompile(`^/metrics/?$`)
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func NewWithConfig(c Config, provider TaskProvider) (Task, error) {
+	plugin := new(metrics)
+	plugin.label = c.Label()
+	plugin.prefix = c.Prefix
+	plugin.middleware = c.Middleware
+	plugin.provider = provider
+
+	// Create metrics
+	plugin.requests = newCounter("router_requests_total", "Number of requests served for each route.", "task", "prefix", "path", "method", "code")
+	plugin.latency = newHistogram("router_request_duration_seconds", "Time taken to serve requests for each route.", defaultBuckets, "task", "prefix", "path", "method")
+	plugin.tokens = newCounter("tokenauth_matches_total", "Number of token values which matched or did not match a token.", "task", "result")
+	plugin.nginx = newCounter("nginx_operations_total", "Number of operations on nginx configurations, and their outcome.", "task", "operation", "result")
+	plugin.packets = newCounter("mdns_packets_total", "Number of mDNS packets sent and received.", "task", "direction")
+
+	// Register handlers
+	router := c.Router.Task.(Router)
+	plugin.router = router
+	if err := router.AddHandler(plugin, rePathMetrics, plugin.MetricsHandler, http.MethodGet); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return plugin, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (plugin *metrics) String() string {
+	str := "<metrics"
+	str += fmt.Sprintf(" label=%q", plugin.label)
+	str += fmt.Sprintf(" prefix=%q", plugin.prefix)
+	if len(plugin.middleware) > 0 {
+		str += fmt.Sprintf(" middleware=%q", plugin.middleware)
+	}
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (plugin *metrics) Prefix() string {
+	return plugin.prefix
+}
+
+func (plugin *metrics) Middleware() []string {
+	return plugin.middleware
+}
+
+/////////////////////////////////////////////////////////////////////
+// HANDLERS
+
+// MetricsHandler serves the metrics in Prometheus text format
+func (plugin *metrics) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(util.ContentTypeKey, contentTypeMetrics)
+	w.WriteHeader(http.StatusOK)
+	plugin.write(w)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// collect updates the metrics from an event emitted by a task
+func (plugin *metrics) collect(key string, evt Event) {
+	plugin.Lock()
+	defer plugin.Unlock()
+
+	result := "success"
+	if evt.Error() != nil {
+		result = "failure"
+	}
+	switch k := evt.Key().(type) {
+	case RouterEventType:
+		if info, ok := evt.Value().(RequestInfo); ok && k == RouterRequest {
+			plugin.requests.inc(key, info.Prefix, info.Path, info.Method, strconv.Itoa(info.Status))
+			plugin.latency.observe(info.Duration.Seconds(), key, info.Prefix, info.Path, info.Method)
+		}
+	case TokenAuthEventType:
+		switch k {
+		case TokenMatch:
+			plugin.tokens.inc(key, "match")
+		case TokenMiss:
+			plugin.tokens.inc(key, "miss")
+		}
+	case NginxEventType:
+		switch k {
+		case NginxCreate:
+			plugin.nginx.inc(key, "create", result)
+		case NginxRevoke:
+			plugin.nginx.inc(key, "revoke", result)
+		case NginxEnable:
+			plugin.nginx.inc(key, "enable", result)
+		case NginxDisable:
+			plugin.nginx.inc(key, "disable", result)
+		case NginxReload:
+			plugin.nginx.inc(key, "reload", result)
+		}
+	case MessageType:
+		switch k {
+		case Send:
+			plugin.packets.inc(key, "sent")
+		case Receive:
+			plugin.packets.inc(key, "received")
+		}
+	}
+}
+
+// write the metrics in Prometheus text format
+func (plugin *metrics) write(w io.Writer) error {
+	plugin.Lock()
+	defer plugin.Unlock()
+
+	if err := plugin.requests.write(w); err != nil {
+		return err
+	}
+	if err := plugin.latency.write(w); err != nil {
+		return err
+	}
+	if err := plugin.tokens.write(w); err != nil {
+		return err
+	}
+	if err := plugin.nginx.write(w); err != nil {
+		return err
+	}
+	return plugin.packets.write(w)
+}
@@ -0,0 +1,215 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	mdns "github.com/mutablelogic/terraform-provider-nginx/pkg/mdns"
+	metrics "github.com/mutablelogic/terraform-provider-nginx/pkg/metrics"
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// EmitConfig is a task configuration for a task which emits events
+// once started
+type EmitConfig struct {
+	Events []iface.Event
+}
+
+type EmitTask struct {
+	event.PubSub
+	EmitConfig
+}
+
+func (c EmitConfig) Name() string {
+	return "emit"
+}
+
+func (c EmitConfig) Label() string {
+	return "test"
+}
+
+func (c EmitConfig) New(context.Context, iface.Provider) (iface.Task, error) {
+	return &EmitTask{EmitConfig: c}, nil
+}
+
+func (t *EmitTask) Run(ctx context.Context) error {
+	time.Sleep(50 * time.Millisecond)
+	for _, evt := range t.Events {
+		t.Emit(evt)
+	}
+	<-ctx.Done()
+	t.Emit(nil)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// TESTS
+
+func Test_Metrics_001(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create tasks
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := provider.New(ctx, metrics.Config{Router: types.Task{Task: router}})
+	if err != nil {
+		t.Fatal(err)
+	} else {
+		t.Log(metrics)
+	}
+	if _, err := provider.New(ctx, EmitConfig{Events: []iface.Event{
+		event.NewEvent(NginxCreate, "default"),
+		event.NewKeyError(NginxReload, errors.New("reload failed")),
+		event.NewEvent(TokenMatch, "admin"),
+		event.NewEvent(TokenMiss, nil),
+		event.NewEvent(Send, nil),
+		event.NewEvent(Receive, nil),
+		event.NewEvent(Receive, nil),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the provider
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go provider.Run(ctx)
+	time.Sleep(10 * time.Millisecond)
+
+	// Make requests, then wait for events to be collected
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		data, _ := io.ReadAll(w.Body)
+		return string(data)
+	}
+	get("/missing")
+	get("/metrics")
+	time.Sleep(100 * time.Millisecond)
+
+	// Check metrics
+	body := get("/metrics")
+	for _, line := range []string{
+		`router_requests_total{task="router.router",prefix="",path="",method="GET",code="404"} 1`,
+		`router_requests_total{task="router.router",prefix="/",path="^/metrics/?$",method="GET",code="200"} 1`,
+		`router_request_duration_seconds_count{task="router.router",prefix="/",path="^/metrics/?$",method="GET"} 1`,
+		`tokenauth_matches_total{task="emit.test",result="match"} 1`,
+		`tokenauth_matches_total{task="emit.test",result="miss"} 1`,
+		`nginx_operations_total{task="emit.test",operation="create",result="success"} 1`,
+		`nginx_operations_total{task="emit.test",operation="reload",result="failure"} 1`,
+		`mdns_packets_total{task="emit.test",direction="sent"} 1`,
+		`mdns_packets_total{task="emit.test",direction="received"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Missing %q in:\n%v", line, body)
+		}
+	}
+}
+
+func Test_Metrics_002(t *testing.T) {
+	provider := provider.New()
+	provider.StreamCap = 1
+	ctx := context.Background()
+
+	// Create tasks, where more events are emitted than can be buffered
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, metrics.Config{Router: types.Task{Task: router}}); err != nil {
+		t.Fatal(err)
+	}
+	events := make([]iface.Event, 1000)
+	for i := range events {
+		events[i] = event.NewEvent(Send, nil)
+	}
+	if _, err := provider.New(ctx, EmitConfig{Events: events}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the provider
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go provider.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	// Check every event was counted
+	w := httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if line := `mdns_packets_total{task="emit.test",direction="sent"} 1000`; !strings.Contains(w.Body.String(), line+"\n") {
+		t.Errorf("Missing %q in:\n%v", line, w.Body.String())
+	}
+}
+
+func Test_Metrics_003(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create tasks, where the mdns task requires a multicast interface
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, metrics.Config{Router: types.Task{Task: router}}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := provider.New(ctx, mdns.Config{})
+	if err != nil {
+		t.Skip("Skipping test:", err)
+	}
+
+	// Run the provider
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	go provider.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	// Send a query, which is also received as multicast is looped back
+	if msg, err := mdns.MessageWithQuestion("_services._dns-sd._udp.local.", net.Interface{}); err != nil {
+		t.Fatal(err)
+	} else if err := task.(mdns.DNSTask).Send(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the counters for packets sent and received have increased
+	re := regexp.MustCompile(`(?m)^mdns_packets_total\{task="[^"]+",direction="(sent|received)"\} ([0-9]+)$`)
+	for {
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		counts := map[string]int{}
+		for _, match := range re.FindAllStringSubmatch(w.Body.String(), -1) {
+			n, _ := strconv.Atoi(match[2])
+			counts[match[1]] += n
+		}
+		if counts["sent"] > 0 && counts["received"] > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Unexpected packet counts %v in:\n%v", counts, w.Body.String())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
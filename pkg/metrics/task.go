@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"context"
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Run collects metrics from the events emitted by tasks until done. Events
+// are observed rather than received on a channel, so none are dropped
+func (plugin *metrics) Run(ctx context.Context) error {
+	defer plugin.provider.ObserveTasks(plugin.collect)()
+
+	<-ctx.Done()
+	plugin.Emit(nil)
+	return ctx.Err()
+}
+
+func (plugin *metrics) Label() string {
+	return plugin.label
+}
+
+// Close removes the handlers from the router, when the task is removed
+// from the provider
+func (plugin *metrics) Close() error {
+	return plugin.router.RemoveHandler(plugin)
+}
@@ -6,6 +6,7 @@ import (
 	// Module imports
 
 	"github.com/mutablelogic/terraform-provider-nginx/pkg/nginx"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth-gateway"
 	"github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
@@ -19,10 +20,13 @@ import (
 // TYPES
 
 type Config struct {
-	Label_ string     `json:"label,omitempty"`
-	Prefix string     `json:"prefix,omitempty"`
-	Nginx  types.Task `json:"nginx"`  // plugin.Nginx
-	Router types.Task `json:"router"` // plugin.Router
+	Label_     string        `hcl:"label,label" json:"label,omitempty"`
+	Prefix     string        `hcl:"prefix,optional" json:"prefix,omitempty"`
+	Middleware []string      `hcl:"middleware,optional" json:"middleware,omitempty"` // Middleware applied to handlers, in order, which defaults to token authentication
+	Nginx      types.Task    `hcl:"nginx,optional" json:"nginx"`                     // plugin.Nginx
+	Router     types.Task    `hcl:"router,optional" json:"router"`                   // plugin.Router
+	Reload     *bool         `hcl:"reload,optional" json:"reload,omitempty"`         // Test and reload nginx after each change, which defaults to true
+	Policy     *types.Policy `hcl:"policy,block" json:"policy,omitempty"`            // Supervision policy for the task
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -50,10 +54,20 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 	if c.Prefix == "" {
 		c.Prefix = "/" + c.Nginx.Label() + DefaultPathSuffix
 	}
+	if c.Middleware == nil {
+		c.Middleware = []string{tokenauth.MiddlewareName}
+	}
+	if c.Reload == nil {
+		reload := true
+		c.Reload = &reload
+	}
 
 	// Check parameters
-	if !util.IsIdentifier(c.Label) {
-		return nil, ErrBadParameter.Withf("label: %q", c.Label)
+	if !util.IsIdentifier(c.Label()) {
+		return nil, ErrBadParameter.Withf("label: %q", c.Label())
+	}
+	if len(c.Middleware) == 0 {
+		return nil, ErrBadParameter.With("middleware: authentication is required")
 	}
 
 	// Return new task
@@ -0,0 +1,61 @@
+package nginx_gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	// Modules
+	multierror "github.com/hashicorp/go-multierror"
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+)
+
+// CreateHandler creates a configuration, which is optionally enabled. Nginx is
+// tested and reloaded, and the configuration is removed if the test fails
+func (plugin *gateway) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	params := context.ReqParams(r)
+	if len(params) != 1 {
+		util.ServeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Decode the request
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.ServeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Create the configuration
+	config, err := plugin.nginx.Create(params[0], []byte(req.Body))
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Enable the configuration, and remove it again on failure
+	if req.Enabled != nil && *req.Enabled {
+		if err := plugin.nginx.Enable(config); err != nil {
+			if err_ := plugin.nginx.Revoke(config); err_ != nil {
+				err = multierror.Append(err, err_)
+			}
+			serveError(w, err)
+			return
+		}
+	}
+
+	// Test and reload, and remove the configuration on failure
+	if err := plugin.apply(r.Context(), func() error {
+		return plugin.nginx.Revoke(config)
+	}); err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Serve response
+	if response, err := configuration(config, true); err != nil {
+		serveError(w, err)
+	} else {
+		util.ServeJSON(w, response, http.StatusCreated, 2)
+	}
+}
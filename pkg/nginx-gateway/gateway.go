@@ -1,30 +1,41 @@
 package nginx_gateway
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"regexp"
+
+	// Module imports
+	multierror "github.com/hashicorp/go-multierror"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
 	. "github.com/mutablelogic/terraform-provider-nginx"
 	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
-	// Module imports
 )
 
 /////////////////////////////////////////////////////////////////////
 // TYPES
 
 type gateway struct {
-	Nginx
+	event.PubSub
+
+	nginx         Nginx
+	router        Router
 	label, prefix string
 	middleware    []string
-	ch            chan Event
+	reload        bool
+	configs       map[string]bool
 }
 
 /////////////////////////////////////////////////////////////////////
 // GLOBALS
 
 var (
-// rePathList         = regexp.MustCompile(`^/$`)
-// rePathCreateRevoke = regexp.MustCompile(`^/(` + util.ReIdentifier + `)/?$`)
+	rePathList = regexp.MustCompile(`^/$`)
+	rePathName = regexp.MustCompile(`^/(` + util.ReIdentifier + `)/?$`)
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -32,15 +43,31 @@ var (
 
 func NewWithConfig(c Config) (Task, error) {
 	plugin := new(gateway)
-	plugin.label = c.Label
+	plugin.label = c.Label()
 	plugin.prefix = c.Prefix
-	plugin.ch = make(chan Event, 100)
-	plugin.Nginx = c.Nginx.(Nginx)
+	plugin.middleware = c.Middleware
+	plugin.reload = c.Reload != nil && *c.Reload
+	plugin.nginx = c.Nginx.Task.(Nginx)
+	plugin.configs = make(map[string]bool)
 
 	// Register handlers
-	//if err := c.Router.(Router).AddHandler(c.Prefix, rePathList, plugin.ListHandler, http.MethodGet); err != nil {
-	//	return nil, err
-	//}
+	router := c.Router.Task.(Router)
+	plugin.router = router
+	if err := router.AddHandler(plugin, rePathList, plugin.ListHandler, http.MethodGet); err != nil {
+		return nil, err
+	}
+	if err := router.AddHandler(plugin, rePathName, plugin.GetHandler, http.MethodGet); err != nil {
+		return nil, err
+	}
+	if err := router.AddHandler(plugin, rePathName, plugin.CreateHandler, http.MethodPost); err != nil {
+		return nil, err
+	}
+	if err := router.AddHandler(plugin, rePathName, plugin.RevokeHandler, http.MethodDelete); err != nil {
+		return nil, err
+	}
+	if err := router.AddHandler(plugin, rePathName, plugin.PatchHandler, http.MethodPatch); err != nil {
+		return nil, err
+	}
 
 	// Return success
 	return plugin, nil
@@ -53,6 +80,7 @@ func (plugin *gateway) String() string {
 	str := "<nginx-gateway"
 	str += fmt.Sprintf(" label=%q", plugin.label)
 	str += fmt.Sprintf(" prefix=%q", plugin.prefix)
+	str += fmt.Sprintf(" reload=%v", plugin.reload)
 	return str + ">"
 }
 
@@ -66,3 +94,22 @@ func (plugin *gateway) Prefix() string {
 func (plugin *gateway) Middleware() []string {
 	return plugin.middleware
 }
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// apply tests the configuration after a change and then reloads nginx. The
+// change is rolled back if the test fails, but not if the reload fails, as
+// the configuration has passed the test
+func (plugin *gateway) apply(ctx context.Context, rollback func() error) error {
+	if !plugin.reload {
+		return nil
+	}
+	if _, err := plugin.nginx.Test(ctx); err != nil {
+		if err_ := rollback(); err_ != nil {
+			err = multierror.Append(err, err_)
+		}
+		return err
+	}
+	return plugin.nginx.Reload(ctx)
+}
@@ -1,9 +1,18 @@
 package nginx_gateway_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -12,8 +21,11 @@ import (
 	gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx-gateway"
 	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
 	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+
 	// Namespace imports
-	//. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
 )
 
 func Test_NginxGateway_001(t *testing.T) {
@@ -29,6 +41,7 @@ func Test_NginxGateway_001(t *testing.T) {
 
 	// Create tasks and add them to the provider
 	nginx, err := provider.New(ctx, nginx.Config{
+		Label_:    "main",
 		Available: "../../etc/test/nginx",
 		Enabled:   tmpdir,
 	})
@@ -43,11 +56,24 @@ func Test_NginxGateway_001(t *testing.T) {
 	} else {
 		t.Log(router)
 	}
-	gateway, err := provider.New(ctx, gateway.Config{Nginx: nginx, Router: router})
+	gw, err := provider.New(ctx, gateway.Config{Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}})
 	if err != nil {
 		t.Fatal(err)
 	} else {
-		t.Log(gateway)
+		t.Log(gw)
+	}
+
+	// The default prefix is derived from the label of the nginx task
+	if prefix := gw.(Gateway).Prefix(); prefix != "/main/v1" {
+		t.Error("Unexpected prefix", prefix)
+	}
+
+	// The default middleware is token authentication, and an empty list is rejected
+	if middleware := gw.(Gateway).Middleware(); len(middleware) != 1 || middleware[0] != "tokenauth" {
+		t.Error("Unexpected middleware", middleware)
+	}
+	if _, err := provider.New(ctx, gateway.Config{Label_: "empty", Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}, Middleware: []string{}}); err == nil {
+		t.Error("Expected error for empty middleware")
 	}
 }
 
@@ -78,7 +104,7 @@ func Test_NginxGateway_002(t *testing.T) {
 	} else {
 		t.Log(router)
 	}
-	gateway, err := provider.New(ctx, gateway.Config{Nginx: nginx, Router: router})
+	gateway, err := provider.New(ctx, gateway.Config{Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}, Middleware: authMiddleware(t, router)})
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -100,7 +126,7 @@ func Test_NginxGateway_002(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		t.Log("Running event handler")
-		for event := range provider.C() {
+		for event := range provider.Sub() {
 			t.Log(event)
 		}
 		t.Log("Finished event handler")
@@ -110,3 +136,230 @@ func Test_NginxGateway_002(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func Test_NginxGateway_003(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create a temporary tree for available and enabled configurations
+	tmpdir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	for _, path := range []string{"sites-available", "sites-enabled"} {
+		if err := os.Mkdir(filepath.Join(tmpdir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create tasks and add them to the provider
+	nginx, err := provider.New(ctx, nginx.Config{Path: tmpdir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reload := false
+	gw, err := provider.New(ctx, gateway.Config{Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}, Middleware: authMiddleware(t, router), Reload: &reload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Make a request and decode the response
+	prefix := gw.(Gateway).Prefix()
+	request := func(t *testing.T, method, path string, body any, code int, v any) {
+		t.Helper()
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(method, prefix+path, bytes.NewReader(data)))
+		if status := w.Result().StatusCode; status != code {
+			t.Fatalf("%v %v: unexpected status code %v: %v", method, path, status, w.Body.String())
+		} else if v != nil {
+			if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		var config gateway.Configuration
+		request(t, http.MethodPost, "/test", map[string]any{"enabled": true, "body": "server {}"}, http.StatusCreated, &config)
+		if config.Name != "test" || !config.Enabled || config.Body != "server {}" {
+			t.Error("Unexpected response: ", config)
+		}
+		request(t, http.MethodPost, "/test", map[string]any{"body": "server {}"}, http.StatusConflict, nil)
+		request(t, http.MethodPost, "/empty", map[string]any{}, http.StatusBadRequest, nil)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		var configs []gateway.Configuration
+		request(t, http.MethodGet, "/", nil, http.StatusOK, &configs)
+		if len(configs) != 1 || configs[0].Name != "test" || !configs[0].Enabled {
+			t.Error("Unexpected response: ", configs)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		var config gateway.Configuration
+		request(t, http.MethodGet, "/test", nil, http.StatusOK, &config)
+		if config.Name != "test" || !config.Enabled || config.Body != "server {}" {
+			t.Error("Unexpected response: ", config)
+		}
+		request(t, http.MethodGet, "/missing", nil, http.StatusNotFound, nil)
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		var config gateway.Configuration
+		request(t, http.MethodPatch, "/test", map[string]any{"enabled": false}, http.StatusOK, &config)
+		if config.Enabled {
+			t.Error("Unexpected response: ", config)
+		} else if _, err := os.Lstat(filepath.Join(tmpdir, "sites-enabled", "test")); !os.IsNotExist(err) {
+			t.Error("Expected configuration to be disabled")
+		}
+		request(t, http.MethodPatch, "/test", map[string]any{"enabled": true}, http.StatusOK, &config)
+		if !config.Enabled {
+			t.Error("Unexpected response: ", config)
+		}
+		request(t, http.MethodPatch, "/test", map[string]any{}, http.StatusBadRequest, nil)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		request(t, http.MethodDelete, "/test", nil, http.StatusOK, nil)
+		request(t, http.MethodDelete, "/test", nil, http.StatusNotFound, nil)
+		if entries, err := os.ReadDir(filepath.Join(tmpdir, "sites-available")); err != nil {
+			t.Error(err)
+		} else if len(entries) != 0 {
+			t.Error("Expected no configurations")
+		}
+	})
+}
+
+func Test_NginxGateway_004(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create a temporary tree for available and enabled configurations
+	tmpdir := t.TempDir()
+	for _, path := range []string{"sites-available", "sites-enabled"} {
+		if err := os.Mkdir(filepath.Join(tmpdir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Stub binary which fails the test when a file exists
+	fail := filepath.Join(tmpdir, "fail")
+	binary := filepath.Join(tmpdir, "nginx")
+	script := fmt.Sprintf("#!/bin/sh\nif [ -e %q ]; then echo 'nginx: [emerg] unknown directive \"foo\" in /etc/nginx/sites-enabled/test:1' >&2; exit 1; fi\n", fail)
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a stub master process, which starts a new worker on SIGHUP
+	master := exec.Command("/bin/sh", "-c", "trap 'sleep 30 &' HUP; sleep 30 & while :; do wait; done")
+	master.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := master.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Kill(-master.Process.Pid, syscall.SIGKILL)
+	pidpath := filepath.Join(tmpdir, "nginx.pid")
+	if err := os.WriteFile(pidpath, []byte(fmt.Sprintln(master.Process.Pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create tasks and add them to the provider
+	nginx, err := provider.New(ctx, nginx.Config{Path: tmpdir, Binary: binary, PidPath: pidpath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw, err := provider.New(ctx, gateway.Config{Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}, Middleware: authMiddleware(t, router)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Count the reload events
+	var reloads atomic.Int32
+	ch := nginx.Sub()
+	defer nginx.Unsub(ch)
+	go func() {
+		for evt := range ch {
+			if evt.Key() == NginxReload && evt.Error() == nil {
+				reloads.Add(1)
+			}
+		}
+	}()
+
+	// Make a request, and check the status code and the number of reloads
+	prefix := gw.(Gateway).Prefix()
+	time.Sleep(100 * time.Millisecond)
+	request := func(t *testing.T, method, path string, body any, code int, n int32) {
+		t.Helper()
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(method, prefix+path, bytes.NewReader(data)))
+		if status := w.Result().StatusCode; status != code {
+			t.Fatalf("%v %v: unexpected status code %v: %v", method, path, status, w.Body.String())
+		}
+		deadline := time.Now().Add(time.Second)
+		for reloads.Load() != n && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if reloads.Load() != n {
+			t.Errorf("%v %v: expected %v reloads, got %v", method, path, n, reloads.Load())
+		}
+	}
+
+	// Changes are tested and reloaded
+	request(t, http.MethodPost, "/test", map[string]any{"enabled": true, "body": "server {}"}, http.StatusCreated, 1)
+	request(t, http.MethodPatch, "/test", map[string]any{"enabled": false}, http.StatusOK, 2)
+
+	// Changes which fail the test are rolled back, and not reloaded
+	if err := os.WriteFile(fail, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	request(t, http.MethodPost, "/other", map[string]any{"enabled": true, "body": "foo;"}, http.StatusBadRequest, 2)
+	if _, err := os.Stat(filepath.Join(tmpdir, "sites-available", "other.conf")); !os.IsNotExist(err) {
+		t.Error("Expected configuration to be removed")
+	}
+	request(t, http.MethodPatch, "/test", map[string]any{"enabled": true}, http.StatusBadRequest, 2)
+	if _, err := os.Lstat(filepath.Join(tmpdir, "sites-enabled", "test")); !os.IsNotExist(err) {
+		t.Error("Expected configuration to be disabled")
+	}
+	request(t, http.MethodDelete, "/test", nil, http.StatusBadRequest, 2)
+	if _, err := os.Stat(filepath.Join(tmpdir, "sites-available", "test.conf")); err != nil {
+		t.Error("Expected configuration to be restored:", err)
+	}
+
+	// Remove the configuration once the test passes
+	if err := os.Remove(fail); err != nil {
+		t.Fatal(err)
+	}
+	request(t, http.MethodDelete, "/test", nil, http.StatusOK, 3)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// authMiddleware adds middleware to the router which allows all requests,
+// and returns the middleware for a gateway
+func authMiddleware(t *testing.T, router Task) []string {
+	t.Helper()
+	if err := router.(Router).AddMiddleware("auth", func(fn http.HandlerFunc) http.HandlerFunc {
+		return fn
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"auth"}
+}
@@ -0,0 +1,120 @@
+package nginx_gateway
+
+import (
+	"errors"
+	"net/http"
+
+	// Modules
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	nginx "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Configuration is the response for a configuration
+type Configuration struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Body    string `json:"body,omitempty"`
+}
+
+// Request is the body of a request to create or modify a configuration
+type Request struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Body    string `json:"body,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// HANDLERS
+
+func (plugin *gateway) ListHandler(w http.ResponseWriter, r *http.Request) {
+	// Enumerate configurations
+	configs, err := plugin.nginx.Enumerate()
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Create response
+	result := make([]Configuration, 0, len(configs))
+	for _, config := range configs {
+		result = append(result, Configuration{Name: config.Name(), Enabled: config.Enabled()})
+	}
+
+	// Serve response
+	util.ServeJSON(w, result, http.StatusOK, 2)
+}
+
+func (plugin *gateway) GetHandler(w http.ResponseWriter, r *http.Request) {
+	params := context.ReqParams(r)
+	if len(params) != 1 {
+		util.ServeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Get the configuration
+	config, err := plugin.get(params[0])
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Serve response
+	if response, err := configuration(config, true); err != nil {
+		serveError(w, err)
+	} else {
+		util.ServeJSON(w, response, http.StatusOK, 2)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// get returns a configuration by name, or ErrNotFound
+func (plugin *gateway) get(name string) (NginxConfig, error) {
+	configs, err := plugin.nginx.Enumerate()
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		if config.Name() == name {
+			return config, nil
+		}
+	}
+	return nil, ErrNotFound.Withf("%q", name)
+}
+
+// configuration returns the response for a configuration, optionally
+// including the body
+func configuration(config NginxConfig, body bool) (Configuration, error) {
+	result := Configuration{Name: config.Name(), Enabled: config.Enabled()}
+	if body {
+		if data, err := config.Read(); err != nil {
+			return result, err
+		} else {
+			result.Body = string(data)
+		}
+	}
+	return result, nil
+}
+
+// serveError serves an error response with a status code based on the error
+func serveError(w http.ResponseWriter, err error) {
+	var diagnostic *nginx.Diagnostic
+	switch {
+	case errors.Is(err, ErrNotFound):
+		util.ServeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrDuplicateEntry):
+		util.ServeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrBadParameter), errors.As(err, &diagnostic):
+		util.ServeError(w, http.StatusBadRequest, err.Error())
+	default:
+		util.ServeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
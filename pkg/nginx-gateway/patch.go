@@ -0,0 +1,68 @@
+package nginx_gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	// Modules
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+)
+
+// PatchHandler enables or disables a configuration. Nginx is tested and
+// reloaded, and the change is reverted if the test fails
+func (plugin *gateway) PatchHandler(w http.ResponseWriter, r *http.Request) {
+	params := context.ReqParams(r)
+	if len(params) != 1 {
+		util.ServeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Decode the request, which requires the enabled field
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.ServeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if req.Enabled == nil {
+		util.ServeError(w, http.StatusBadRequest, "missing enabled field")
+		return
+	}
+
+	// Get the configuration
+	config, err := plugin.get(params[0])
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Enable or disable the configuration if the state has changed, then
+	// test and reload, and revert the change on failure
+	if *req.Enabled && !config.Enabled() {
+		if err := plugin.nginx.Enable(config); err != nil {
+			serveError(w, err)
+			return
+		} else if err := plugin.apply(r.Context(), func() error {
+			return plugin.nginx.Disable(config)
+		}); err != nil {
+			serveError(w, err)
+			return
+		}
+	} else if !*req.Enabled && config.Enabled() {
+		if err := plugin.nginx.Disable(config); err != nil {
+			serveError(w, err)
+			return
+		} else if err := plugin.apply(r.Context(), func() error {
+			return plugin.nginx.Enable(config)
+		}); err != nil {
+			serveError(w, err)
+			return
+		}
+	}
+
+	// Serve response
+	if response, err := configuration(config, false); err != nil {
+		serveError(w, err)
+	} else {
+		util.ServeJSON(w, response, http.StatusOK, 2)
+	}
+}
@@ -0,0 +1,48 @@
+package nginx_gateway
+
+import (
+	"net/http"
+
+	// Modules
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+)
+
+// RevokeHandler removes a configuration. Nginx is tested and reloaded, and
+// the configuration is created again if the test fails
+func (plugin *gateway) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	params := context.ReqParams(r)
+	if len(params) != 1 {
+		util.ServeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Get the configuration, and read it so it can be restored
+	config, err := plugin.get(params[0])
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	name, enabled := config.Name(), config.Enabled()
+	data, err := config.Read()
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	// Revoke the configuration, then test and reload, and restore the
+	// configuration on failure
+	if err := plugin.nginx.Revoke(config); err != nil {
+		serveError(w, err)
+	} else if err := plugin.apply(r.Context(), func() error {
+		config, err := plugin.nginx.Create(name, data)
+		if err == nil && enabled {
+			err = plugin.nginx.Enable(config)
+		}
+		return err
+	}); err != nil {
+		serveError(w, err)
+	} else {
+		util.ServeEmpty(w, http.StatusOK)
+	}
+}
@@ -2,12 +2,10 @@ package nginx_gateway
 
 import (
 	"context"
-	"fmt"
 	"time"
 
-	// Namespace imports
-	. "github.com/mutablelogic/terraform-provider-nginx"
-	"github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	// Module imports
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -17,14 +15,14 @@ import (
 func (plugin *gateway) Run(ctx context.Context) error {
 	ticker := time.NewTimer(100 * time.Millisecond)
 	defer ticker.Stop()
-	defer plugin.stop()
 	for {
 		select {
 		case <-ctx.Done():
+			plugin.Emit(nil)
 			return ctx.Err()
 		case <-ticker.C:
 			if err := plugin.enumerate(); err != nil {
-				event.NewError(err).Emit(plugin.ch)
+				plugin.Emit(event.NewError(err))
 			}
 			ticker.Reset(time.Second)
 		}
@@ -36,28 +34,37 @@ func (plugin *gateway) Label() string {
 	return plugin.label
 }
 
-// Return event channel
-func (plugin *gateway) C() <-chan Event {
-	return plugin.ch
-}
-
 /////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func (plugin *gateway) stop() {
-	close(plugin.ch)
-}
-
+// enumerate configurations and emit an event for each configuration
+// which has been added, removed, enabled or disabled since the last
+// enumeration
 func (plugin *gateway) enumerate() error {
-	// TODO: Only enumerate on first run or if any folder has changed
-	configs, err := plugin.Enumerate()
+	configs, err := plugin.nginx.Enumerate()
 	if err != nil {
 		return err
 	}
+	seen := make(map[string]bool, len(configs))
 	for _, config := range configs {
-		fmt.Println("emit", config)
 		name := config.Name()
-		event.NewEvent(name, config).Emit(plugin.ch)
+		seen[name] = true
+		if enabled, exists := plugin.configs[name]; !exists || enabled != config.Enabled() {
+			plugin.configs[name] = config.Enabled()
+			plugin.Emit(event.NewEvent(name, config))
+		}
+	}
+	for name := range plugin.configs {
+		if !seen[name] {
+			delete(plugin.configs, name)
+			plugin.Emit(event.NewEvent(name, nil))
+		}
 	}
 	return nil
 }
+
+// Close removes the handlers from the router, when the task is removed
+// from the provider
+func (plugin *gateway) Close() error {
+	return plugin.router.RemoveHandler(plugin)
+}
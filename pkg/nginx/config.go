@@ -4,8 +4,10 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Modules
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
@@ -17,12 +19,16 @@ import (
 // TYPES
 
 type Config struct {
-	Label_    string `hcl:"label,label" json:"label,omitempty"`            // Label for the configuration
-	Path      string `hcl:"conf_path" json:"conf_path"`                    // Root path for the configuration
-	PidPath   string `hcl:"pid_path,optional" json:"pid_path"`             // Path to the PID file
-	Available string `hcl:"available_path,optional" json:"available_path"` // Path to available sites, under root
-	Recursive bool   `hcl:"available_recursive,optional" json:"recursive"` // Recursively search in available folder
-	Enabled   string `hcl:"enabled_path,optional" json:"enabled_path"`     // Path to enabled sites, under root
+	Label_    string        `hcl:"label,label" json:"label,omitempty"`            // Label for the configuration
+	Path      string        `hcl:"conf_path" json:"conf_path"`                    // Root path for the configuration
+	PidPath   string        `hcl:"pid_path,optional" json:"pid_path"`             // Path to the PID file
+	Available string        `hcl:"available_path,optional" json:"available_path"` // Path to available sites, under root
+	Recursive bool          `hcl:"available_recursive,optional" json:"recursive"` // Recursively search in available folder
+	Enabled   string        `hcl:"enabled_path,optional" json:"enabled_path"`     // Path to enabled sites, under root
+	Binary    string        `hcl:"binary,optional" json:"binary"`                 // Path to the nginx binary
+	Conf      string        `hcl:"conf,optional" json:"conf"`                     // Main configuration file, under root
+	Rollback  bool          `hcl:"rollback,optional" json:"rollback"`             // Test configuration on create and enable, and roll back on failure
+	Policy    *types.Policy `hcl:"policy,block" json:"policy,omitempty"`          // Supervision policy for the task
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -33,9 +39,14 @@ const (
 	defaultAvailable = "sites-available"
 	defaultEnabled   = "sites-enabled"
 	defaultPidPath   = "/run/nginx.pid"
+	defaultBinary    = "nginx"
+	defaultConf      = "nginx.conf"
 	defaultExt       = ".conf"
 	defaultFileMode  = 0644
 	pathSeparator    = string(os.PathSeparator)
+
+	defaultEventChannelCapacity = 1000
+	defaultTestTimeout          = 10 * time.Second
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -95,6 +106,19 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 		c.Enabled = filepath.Join(c.Path, c.Enabled)
 	}
 
+	// Set binary path, which is searched for when not absolute
+	if c.Binary == "" {
+		c.Binary = defaultBinary
+	}
+
+	// Set main configuration file
+	if c.Conf == "" {
+		c.Conf = defaultConf
+	}
+	if !filepath.IsAbs(c.Conf) {
+		c.Conf = filepath.Join(c.Path, c.Conf)
+	}
+
 	// Return configuration
 	return NewWithConfig(c)
 }
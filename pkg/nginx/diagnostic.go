@@ -0,0 +1,105 @@
+package nginx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Diagnostic struct {
+	Level    string `json:"level"`
+	File_    string `json:"file,omitempty"`
+	Line_    int    `json:"line,omitempty"`
+	Message_ string `json:"message"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// nginx: [emerg] unknown directive "foo" in /etc/nginx/sites-enabled/test:1
+	reDiagnostic = regexp.MustCompile(`^nginx: \[(\w+)\] (.*?)(?: in (\S+):(\d+))?$`)
+)
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (d *Diagnostic) String() string {
+	str := "<nginx-diagnostic"
+	str += fmt.Sprintf(" level=%q", d.Level)
+	if d.File_ != "" {
+		str += fmt.Sprintf(" file=%q", d.File_)
+	}
+	if d.Line_ != 0 {
+		str += fmt.Sprintf(" line=%d", d.Line_)
+	}
+	str += fmt.Sprintf(" message=%q", d.Message_)
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the file which caused the diagnostic, or empty string
+func (d *Diagnostic) File() string {
+	return d.File_
+}
+
+// Return the line number within the file, or zero
+func (d *Diagnostic) Line() int {
+	return d.Line_
+}
+
+// Return the diagnostic message
+func (d *Diagnostic) Message() string {
+	return d.Message_
+}
+
+// Return true if the diagnostic level is an error, rather than a warning
+// or notice
+func (d *Diagnostic) IsError() bool {
+	switch d.Level {
+	case "warn", "notice", "info", "debug":
+		return false
+	default:
+		return true
+	}
+}
+
+// Implement the error interface
+func (d *Diagnostic) Error() string {
+	if d.File_ == "" {
+		return d.Message_
+	} else {
+		return fmt.Sprintf("%s:%d: %s", d.File_, d.Line_, d.Message_)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parseDiagnostics returns warnings and errors from the output of nginx -t
+func parseDiagnostics(data []byte) []NginxDiagnostic {
+	var result []NginxDiagnostic
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := reDiagnostic.FindStringSubmatch(line); match != nil {
+			d := &Diagnostic{Level: match[1], File_: match[3], Message_: match[2]}
+			if match[4] != "" {
+				d.Line_, _ = strconv.Atoi(match[4])
+			}
+			result = append(result, d)
+		}
+	}
+	return result
+}
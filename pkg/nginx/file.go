@@ -1,6 +1,7 @@
 package nginx
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
@@ -36,7 +37,20 @@ func NewFile(path string, info fs.FileInfo) *File {
 }
 
 func CreateFile(path string, data []byte) (*File, error) {
-	if err := ioutil.WriteFile(path, data, defaultFileMode); err != nil {
+	// Create the file exclusively, so an existing file is never overwritten
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, defaultFileMode)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, ErrDuplicateEntry.With(filepath.Base(path))
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		os.Remove(path)
 		return nil, err
 	}
 
@@ -132,7 +146,7 @@ func (f *File) Disable() error {
 // Delete the file
 func (f *File) Revoke() error {
 	var result error
-	if f.enabled != "" {
+	if f.enabled != "" && f.enabled != f.path {
 		if err := os.Remove(f.enabled); err != nil {
 			result = multierror.Append(result, err)
 		}
@@ -1,13 +1,18 @@
 package nginx
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	// Modules
 	multierror "github.com/hashicorp/go-multierror"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
@@ -20,7 +25,15 @@ import (
 // TYPES
 
 type nginx struct {
+	event.PubSub
+	sync.Mutex
+
+	label     string
 	root      string
+	binary    string
+	conf      string
+	pidpath   string
+	rollback  bool
 	available *Folder
 	enabled   *Folder
 }
@@ -30,7 +43,13 @@ type nginx struct {
 
 func NewWithConfig(c Config) (Task, error) {
 	r := new(nginx)
+	r.Cap = defaultEventChannelCapacity
+	r.label = c.Label()
 	r.root = c.Path
+	r.binary = c.Binary
+	r.conf = c.Conf
+	r.pidpath = c.PidPath
+	r.rollback = c.Rollback
 
 	// Set up available folder
 	if folder, err := NewFolder(c.Available, c.Recursive); err != nil {
@@ -60,6 +79,12 @@ func (r *nginx) String() string {
 	}
 	str += fmt.Sprintf(" available=%q", r.available.RelPath(r.root))
 	str += fmt.Sprintf(" enabled=%q", r.enabled.RelPath(r.root))
+	if r.binary != "" {
+		str += fmt.Sprintf(" binary=%q", r.binary)
+	}
+	if r.rollback {
+		str += " rollback"
+	}
 	return str + ">"
 }
 
@@ -116,9 +141,9 @@ func (r *nginx) Enumerate() ([]NginxConfig, error) {
 		hash := util.MD5Hash(data)
 		if configfile, exists := config[hash]; !exists {
 			config[hash] = file
-			file.SetEnabled(configfile.Path())
+			file.SetEnabled(file.Path())
 		} else {
-			configfile.SetEnabled(configfile.Path())
+			configfile.SetEnabled(file.Path())
 		}
 	}
 
@@ -132,12 +157,19 @@ func (r *nginx) Enumerate() ([]NginxConfig, error) {
 	return configs, nil
 }
 
-// Enable a configuration
-func (r *nginx) Enable(file NginxConfig) error {
+// Enable a configuration. If rollback is set, then the configuration is
+// tested and disabled again if the test fails
+func (r *nginx) Enable(file NginxConfig) (err error) {
 	file_, ok := file.(*File)
 	if !ok || file_ == nil {
 		return ErrBadParameter
 	}
+	defer func() {
+		r.emit(NginxEnable, file_.Name(), err)
+	}()
+
+	r.Lock()
+	defer r.Unlock()
 
 	// Create a path for the file, based on the existing filename
 	enabled_path := filepath.Join(r.enabled.RelPath(""), file_.Name())
@@ -147,6 +179,18 @@ func (r *nginx) Enable(file NginxConfig) error {
 		file_.SetEnabled(enabled_path)
 	}
 
+	// Test the configuration, and disable on failure
+	if r.rollback {
+		if _, err := r.test(context.Background()); err != nil {
+			var result error
+			result = multierror.Append(result, err)
+			if err := file_.Disable(); err != nil {
+				result = multierror.Append(result, err)
+			}
+			return result
+		}
+	}
+
 	// Return success
 	return nil
 }
@@ -157,28 +201,51 @@ func (r *nginx) Disable(file NginxConfig) error {
 	if !ok || file_ == nil {
 		return ErrBadParameter
 	}
-	return file_.Disable()
+	err := file_.Disable()
+	r.emit(NginxDisable, file_.Name(), err)
+	return err
 }
 
 // Create a configuration
-func (r *nginx) Create(name string, data []byte) (NginxConfig, error) {
+func (r *nginx) Create(name string, data []byte) (_ NginxConfig, err error) {
 	// Check parameters
-	name = strings.TrimPrefix(name, defaultExt)
+	name = strings.TrimSuffix(name, defaultExt)
 	if !util.IsIdentifier(name) {
 		return nil, ErrBadParameter.Withf("Invalid name: %q", name)
 	}
 	if len(data) == 0 {
 		return nil, ErrBadParameter.Withf("Invalid data")
 	}
+	defer func() {
+		r.emit(NginxCreate, name, err)
+	}()
+
+	r.Lock()
+	defer r.Unlock()
 
-	// If path already exists, then error
+	// Create file, which fails if the path already exists
 	path := filepath.Join(r.available.RelPath(""), name+defaultExt)
-	if _, err := os.Stat(path); err == nil {
+	file, err := CreateFile(path, data)
+	if errors.Is(err, ErrDuplicateEntry) {
 		return nil, ErrDuplicateEntry.With(name)
+	} else if err != nil {
+		return nil, err
 	}
 
-	// Create file and return it
-	return CreateFile(path, data)
+	// Test the configuration, and remove the file on failure
+	if r.rollback {
+		if _, err := r.test(context.Background()); err != nil {
+			var result error
+			result = multierror.Append(result, err)
+			if err := file.Revoke(); err != nil {
+				result = multierror.Append(result, err)
+			}
+			return nil, result
+		}
+	}
+
+	// Return success
+	return file, nil
 }
 
 // Revoke a configuration
@@ -187,5 +254,86 @@ func (r *nginx) Revoke(file NginxConfig) error {
 	if !ok || file_ == nil {
 		return ErrBadParameter
 	}
-	return file_.Revoke()
+	err := file_.Revoke()
+	r.emit(NginxRevoke, file_.Name(), err)
+	return err
+}
+
+// Test the configuration, and return any diagnostics. Returns
+// an error if the configuration test failed
+func (r *nginx) Test(ctx context.Context) ([]NginxDiagnostic, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.test(ctx)
+}
+
+// Reload the configuration by sending SIGHUP to the master process, and
+// return when the reload has been confirmed or the context is done. An
+// event is emitted on success or failure, once the lock is released
+func (r *nginx) Reload(ctx context.Context) error {
+	r.Lock()
+	pid, err := r.reload(ctx)
+	r.Unlock()
+
+	// Emit the event
+	if err != nil {
+		r.Emit(event.NewKeyError(NginxReload, err))
+	} else {
+		r.Emit(event.NewEvent(NginxReload, pid))
+	}
+
+	// Return any errors
+	return err
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// emit an event for an operation on a configuration, which has an error
+// if the operation failed
+func (r *nginx) emit(op NginxEventType, name string, err error) {
+	if err != nil {
+		r.Emit(event.NewKeyError(op, err))
+	} else {
+		r.Emit(event.NewEvent(op, name))
+	}
+}
+
+// test runs "nginx -t" against the main configuration file, and parses
+// the output for warnings and errors. The test is cancelled after a timeout
+// if the context does not have a deadline
+func (r *nginx) test(ctx context.Context) ([]NginxDiagnostic, error) {
+	// Set a timeout if the context does not have a deadline
+	if _, exists := ctx.Deadline(); !exists {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTestTimeout)
+		defer cancel()
+	}
+
+	// Run the test
+	cmd := exec.CommandContext(ctx, r.binary, "-t", "-c", r.conf)
+	output, err := cmd.CombinedOutput()
+	diagnostics := parseDiagnostics(output)
+
+	// Return any error which is not due to a failed test
+	var exitErr *exec.ExitError
+	if err == nil {
+		return diagnostics, nil
+	} else if !errors.As(err, &exitErr) {
+		return nil, err
+	}
+
+	// Collect the diagnostics which caused the failure
+	var result error
+	for _, diagnostic := range diagnostics {
+		if d, ok := diagnostic.(*Diagnostic); ok && d.IsError() {
+			result = multierror.Append(result, d)
+		}
+	}
+	if result == nil {
+		result = ErrUnexpectedResponse.Withf("%v: %v", r.binary, strings.TrimSpace(string(output)))
+	}
+
+	// Return diagnostics and failure
+	return diagnostics, result
 }
@@ -2,8 +2,13 @@ package nginx_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -12,7 +17,8 @@ import (
 	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
 
 	// Namespace imports
-	//. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/djthorpe/go-errors"
+	. "github.com/mutablelogic/terraform-provider-nginx"
 	. "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx"
 )
 
@@ -126,3 +132,247 @@ func Test_Nginx_002(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func Test_Nginx_003(t *testing.T) {
+	// Set up temporary folders for available, enabled and the nginx binary
+	tmpdir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	available, enabled := MkdirAll(t, tmpdir, "available"), MkdirAll(t, tmpdir, "enabled")
+
+	// Stub binary which always passes the test
+	p := provider.New()
+	nginx, err := p.New(context.Background(), Config{
+		Available: available,
+		Enabled:   enabled,
+		Binary:    StubBinary(t, tmpdir, "nginx: [warn] conflicting server name \"test\" on 0.0.0.0:80, ignored", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test the configuration
+	diags, err := nginx.(plugin.Nginx).Test(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(diags) != 1 {
+		t.Fatal("Expected one diagnostic, got", diags)
+	} else if diags[0].Message() != "conflicting server name \"test\" on 0.0.0.0:80, ignored" {
+		t.Errorf("Unexpected message: %q", diags[0].Message())
+	} else if diags[0].File() != "" || diags[0].Line() != 0 {
+		t.Error("Unexpected file or line: ", diags[0])
+	}
+}
+
+func Test_Nginx_004(t *testing.T) {
+	// Set up temporary folders for available, enabled and the nginx binary
+	tmpdir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	available, enabled := MkdirAll(t, tmpdir, "available"), MkdirAll(t, tmpdir, "enabled")
+
+	// Stub binary which always fails the test
+	p := provider.New()
+	nginx, err := p.New(context.Background(), Config{
+		Available: available,
+		Enabled:   enabled,
+		Binary:    StubBinary(t, tmpdir, "nginx: [emerg] unknown directive \"foo\" in /etc/nginx/sites-enabled/test:3", 1),
+		Rollback:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test the configuration
+	diags, err := nginx.(plugin.Nginx).Test(context.Background())
+	if err == nil {
+		t.Fatal("Expected test to fail")
+	} else if len(diags) != 1 {
+		t.Fatal("Expected one diagnostic, got", diags)
+	} else if diags[0].File() != "/etc/nginx/sites-enabled/test" || diags[0].Line() != 3 {
+		t.Error("Unexpected file or line: ", diags[0])
+	} else if diags[0].Message() != "unknown directive \"foo\"" {
+		t.Errorf("Unexpected message: %q", diags[0].Message())
+	}
+
+	// Creating a configuration should fail, and the file should be removed
+	if _, err := nginx.(plugin.Nginx).Create("test", []byte("foo;")); err == nil {
+		t.Error("Expected create to fail")
+	} else if _, err := os.Stat(filepath.Join(available, "test.conf")); !os.IsNotExist(err) {
+		t.Error("Expected file to be removed on rollback")
+	}
+
+	// Enabling an existing configuration should fail, and the configuration
+	// should be disabled again
+	if err := os.WriteFile(filepath.Join(available, "test.conf"), []byte("foo;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configs, err := nginx.(plugin.Nginx).Enumerate()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(configs) != 1 {
+		t.Fatal("Expected one configuration, got", configs)
+	}
+	if err := nginx.(plugin.Nginx).Enable(configs[0]); err == nil {
+		t.Error("Expected enable to fail")
+	} else if configs[0].Enabled() {
+		t.Error("Expected configuration to be disabled on rollback")
+	} else if _, err := os.Lstat(filepath.Join(enabled, "test.conf")); !os.IsNotExist(err) {
+		t.Error("Expected link to be removed on rollback")
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func MkdirAll(t *testing.T, root, name string) string {
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// StubBinary writes a script which writes output to stderr and exits with
+// a status code, in place of the nginx binary
+func StubBinary(t *testing.T, root, output string, status int) string {
+	path := filepath.Join(root, "nginx")
+	script := fmt.Sprintf("#!/bin/sh\necho %q >&2\nexit %d\n", output, status)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_Nginx_005(t *testing.T) {
+	// Set up temporary folders for available, enabled and the PID file
+	tmpdir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	available, enabled := MkdirAll(t, tmpdir, "available"), MkdirAll(t, tmpdir, "enabled")
+
+	// Start a stub master process, which starts a new worker on SIGHUP
+	master := exec.Command("/bin/sh", "-c", "trap 'sleep 30 &' HUP; sleep 30 & while :; do wait; done")
+	master.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := master.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Kill(-master.Process.Pid, syscall.SIGKILL)
+
+	// Write the PID file
+	pidpath := filepath.Join(tmpdir, "nginx.pid")
+	if err := os.WriteFile(pidpath, []byte(fmt.Sprintln(master.Process.Pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the task
+	p := provider.New()
+	nginx, err := p.New(context.Background(), Config{
+		Available: available,
+		Enabled:   enabled,
+		PidPath:   pidpath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Receive events from the task
+	ch := nginx.Sub()
+	evts := make(chan Event, 10)
+	go func() {
+		for evt := range ch {
+			evts <- evt
+		}
+	}()
+
+	// Reload, and check for the event
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	time.Sleep(100 * time.Millisecond)
+	if err := nginx.(plugin.Nginx).Reload(ctx); err != nil {
+		t.Fatal(err)
+	} else if evt := <-evts; evt.Error() != nil {
+		t.Error(evt.Error())
+	} else if evt.Key() != plugin.NginxReload || evt.Value() != master.Process.Pid {
+		t.Error("Unexpected event: ", evt)
+	}
+
+	// Reload with an invalid PID file should fail
+	if err := os.WriteFile(pidpath, []byte("invalid"), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := nginx.(plugin.Nginx).Reload(ctx); err == nil {
+		t.Error("Expected reload to fail")
+	} else if evt := <-evts; evt.Error() == nil {
+		t.Error("Expected error event, got", evt)
+	}
+
+	// Unsubscribe from events
+	nginx.Unsub(ch)
+}
+
+func Test_Nginx_006(t *testing.T) {
+	// Set up temporary folders for available and enabled
+	tmpdir, err := os.MkdirTemp("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	available, enabled := MkdirAll(t, tmpdir, "available"), MkdirAll(t, tmpdir, "enabled")
+
+	p := provider.New()
+	nginx, err := p.New(context.Background(), Config{
+		Available: available,
+		Enabled:   enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the same configuration concurrently, only one should succeed
+	const n = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, err := nginx.(plugin.Nginx).Create("test", []byte(fmt.Sprint("# ", i)))
+			errs <- err
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	var success int
+	for err := range errs {
+		if err == nil {
+			success++
+		} else if !errors.Is(err, ErrDuplicateEntry) {
+			t.Error("Unexpected error: ", err)
+		}
+	}
+	if success != 1 {
+		t.Error("Expected exactly one create to succeed, got", success)
+	}
+
+	// Creating a file over an existing one should fail and leave it untouched
+	path := filepath.Join(available, "existing.conf")
+	if err := os.WriteFile(path, []byte("# existing"), 0644); err != nil {
+		t.Fatal(err)
+	} else if _, err := CreateFile(path, []byte("# new")); !errors.Is(err, ErrDuplicateEntry) {
+		t.Error("Expected ErrDuplicateEntry, got", err)
+	} else if data, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "# existing" {
+		t.Errorf("Unexpected file contents: %q", data)
+	}
+}
@@ -0,0 +1,136 @@
+package nginx
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	procPath             = "/proc"
+	reloadPollInterval   = 100 * time.Millisecond
+	defaultReloadTimeout = 10 * time.Second
+)
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// reload sends SIGHUP to the master process, and then waits until either
+// a new generation of worker processes has been started or the PID file
+// has been modified. Returns the PID of the master process on success
+func (r *nginx) reload(ctx context.Context) (int, error) {
+	// Set a timeout if the context does not have a deadline
+	if _, exists := ctx.Deadline(); !exists {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultReloadTimeout)
+		defer cancel()
+	}
+
+	// Read the PID and record the current state
+	pid, modtime, err := readPid(r.pidpath)
+	if err != nil {
+		return 0, err
+	}
+
+	// When the process table cannot be read, there is no baseline set of
+	// workers to compare against, so only the PID file is used to confirm
+	workers, err := childPids(pid)
+	if err != nil {
+		workers = nil
+	}
+
+	// Send the signal
+	if proc, err := os.FindProcess(pid); err != nil {
+		return 0, err
+	} else if err := proc.Signal(syscall.SIGHUP); err != nil {
+		return 0, err
+	}
+
+	// Wait for confirmation
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ErrUnexpectedResponse.Withf("reload not confirmed for pid %d: %v", pid, ctx.Err())
+		case <-ticker.C:
+			if _, modtime_, err := readPid(r.pidpath); err == nil && !modtime_.Equal(modtime) {
+				return pid, nil
+			}
+			if workers == nil {
+				continue
+			}
+			children, err := childPids(pid)
+			if err != nil {
+				continue
+			}
+			for child := range children {
+				if _, exists := workers[child]; !exists {
+					return pid, nil
+				}
+			}
+		}
+	}
+}
+
+// readPid returns the process id and modification time from a PID file
+func readPid(path string) (int, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, time.Time{}, ErrBadParameter.Withf("invalid pid in %q", path)
+	}
+
+	// Return success
+	return pid, info.ModTime(), nil
+}
+
+// childPids returns the set of processes which have a parent process id. Returns
+// an error if the process table cannot be read
+func childPids(ppid int) (map[int]struct{}, error) {
+	result := make(map[int]struct{})
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(procPath, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// The command name is in brackets and may contain spaces, so the
+		// parent process id is the second field after the closing bracket
+		if i := bytes.LastIndexByte(data, ')'); i >= 0 {
+			if fields := strings.Fields(string(data[i+1:])); len(fields) > 1 {
+				if parent, err := strconv.Atoi(fields[1]); err == nil && parent == ppid {
+					result[pid] = struct{}{}
+				}
+			}
+		}
+	}
+
+	// Return success
+	return result, nil
+}
@@ -2,9 +2,6 @@ package nginx
 
 import (
 	"context"
-
-	// Namespace imports
-	. "github.com/mutablelogic/terraform-provider-nginx"
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -13,10 +10,15 @@ import (
 // Run until done
 func (r *nginx) Run(ctx context.Context) error {
 	<-ctx.Done()
+
+	// Close subscriber channels
+	r.Emit(nil)
+
+	// Return success
 	return ctx.Err()
 }
 
-// Return event channel
-func (*nginx) C() <-chan Event {
-	return nil
+// Label returns the label of the task
+func (r *nginx) Label() string {
+	return r.label
 }
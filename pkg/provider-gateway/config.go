@@ -0,0 +1,86 @@
+package provider_gateway
+
+import (
+	"context"
+	"time"
+
+	// Module imports
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth-gateway"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Config struct {
+	Label_     string         `hcl:"label,label" json:"label,omitempty"`
+	Prefix     string         `hcl:"prefix,optional" json:"prefix,omitempty"`
+	Middleware []string       `hcl:"middleware,optional" json:"middleware,omitempty"` // Middleware applied to handlers, in order, which defaults to admin token authentication
+	Router     types.Task     `hcl:"router,optional" json:"router"`                   // plugin.Router
+	Heartbeat  types.Duration `hcl:"heartbeat,optional" json:"heartbeat,omitempty"`   // Interval between heartbeats on event streams
+	Policy     *types.Policy  `hcl:"policy,block" json:"policy,omitempty"`            // Supervision policy for the task
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DefaultName        = "provider-gateway"
+	DefaultLabelSuffix = "-gw"
+	DefaultPathSuffix  = "/v1"
+	DefaultLabel       = "provider" + DefaultLabelSuffix
+	DefaultHeartbeat   = 15 * time.Second
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
+	// Check arguments
+	if _, ok := c.Router.Task.(Router); c.Router.Task == nil || !ok {
+		return nil, ErrBadParameter.With("router")
+	}
+	if _, ok := provider.(TaskProvider); !ok {
+		return nil, ErrBadParameter.With("provider")
+	}
+
+	// Set configuration defaults
+	if c.Prefix == "" {
+		c.Prefix = "/provider" + DefaultPathSuffix
+	}
+	if c.Heartbeat <= 0 {
+		c.Heartbeat = types.Duration(DefaultHeartbeat)
+	}
+	if c.Middleware == nil {
+		c.Middleware = []string{tokenauth.MiddlewareAdminName}
+	}
+
+	// Check parameters
+	if !util.IsIdentifier(c.Label()) {
+		return nil, ErrBadParameter.Withf("label: %q", c.Label())
+	}
+	if len(c.Middleware) == 0 {
+		return nil, ErrBadParameter.With("middleware: authentication is required")
+	}
+
+	// Return new task
+	return NewWithConfig(c, provider.(TaskProvider))
+}
+
+func (c Config) Name() string {
+	return DefaultName
+}
+
+func (c Config) Label() string {
+	if c.Label_ == "" {
+		return DefaultLabel
+	} else {
+		return c.Label_
+	}
+}
@@ -0,0 +1,10 @@
+// provider_gateway plugin is a gateway for introspection of the provider. It
+// provides HTTP handlers which list the tasks within the provider, their state
+// and the recent events emitted by each task, and which stream events from
+// tasks as Server-Sent Events.
+//
+// The handlers require authentication, so the middleware defaults to the
+// admin token authentication from the tokenauth gateway, and an empty list
+// of middleware is refused. The values of sensitive events, such as those
+// from token authentication, are redacted by the provider.
+package provider_gateway
@@ -0,0 +1,105 @@
+package provider_gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	// Modules
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+)
+
+/////////////////////////////////////////////////////////////////////
+// HANDLERS
+
+// EventsHandler streams events from tasks as Server-Sent Events, until the
+// client disconnects or the provider stops. The events can be filtered with
+// one or more "key" and "label" query parameters, and a comment is sent on
+// the stream as a heartbeat
+func (plugin *gateway) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		util.ServeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	// Subscribe to events, and unsubscribe when the client disconnects
+	query := r.URL.Query()
+	keys, labels := query["key"], query["label"]
+	ch := plugin.provider.SubTasks()
+	defer plugin.provider.UnsubTasks(ch)
+
+	// Write headers
+	w.Header().Set(util.ContentTypeKey, util.ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Stream events
+	ticker := time.NewTicker(plugin.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case evt, ok := <-ch:
+			if !ok {
+				return
+			}
+			key, _ := evt.Key().(string)
+			if !match(key, keys, labels) {
+				continue
+			}
+			if err := writeEvent(w, key, evt.Value().(Event)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// match returns true if there are no filters, or the task key matches
+// one of the keys or the label of the task matches one of the labels
+func match(key string, keys, labels []string) bool {
+	if len(keys) == 0 && len(labels) == 0 {
+		return true
+	}
+	for _, key_ := range keys {
+		if key == key_ {
+			return true
+		}
+	}
+	_, label, _ := strings.Cut(key, ".")
+	for _, label_ := range labels {
+		if label == label_ {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEvent writes an event emitted by a task as JSON data
+func writeEvent(w http.ResponseWriter, key string, evt Event) error {
+	response := eventResponse(evt)
+	response.Task = key
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,91 @@
+package provider_gateway
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	// Module imports
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type gateway struct {
+	event.PubSub
+
+	provider      TaskProvider
+	router        Router
+	label, prefix string
+	middleware    []string
+	heartbeat     time.Duration
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	rePathList   = regexp.MustCompile(`^/tasks/?$`)
+	rePathEvents = regexp.MustCompile(`^/events/?$`)
+	rePathTask   = regexp.MustCompile(`^/tasks/(` + util.ReIdentifier + `)\.(` + util.ReIdentifier + `)/?$`)
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func NewWithConfig(c Config, provider TaskProvider) (Task, error) {
+	plugin := new(gateway)
+	plugin.label = c.Label()
+	plugin.prefix = c.Prefix
+	plugin.middleware = c.Middleware
+	plugin.provider = provider
+	plugin.heartbeat = time.Duration(c.Heartbeat)
+
+	// Register handlers
+	router := c.Router.Task.(Router)
+	plugin.router = router
+	if err := router.AddHandler(plugin, rePathList, plugin.ListHandler, http.MethodGet); err != nil {
+		return nil, err
+	}
+	if err := router.AddHandler(plugin, rePathTask, plugin.GetHandler, http.MethodGet); err != nil {
+		return nil, err
+	}
+	if err := router.AddHandler(plugin, rePathEvents, plugin.EventsHandler, http.MethodGet); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return plugin, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (plugin *gateway) String() string {
+	str := "<provider-gateway"
+	str += fmt.Sprintf(" label=%q", plugin.label)
+	str += fmt.Sprintf(" prefix=%q", plugin.prefix)
+	str += fmt.Sprintf(" heartbeat=%v", plugin.heartbeat)
+	if len(plugin.middleware) > 0 {
+		str += fmt.Sprintf(" middleware=%q", plugin.middleware)
+	}
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (plugin *gateway) Prefix() string {
+	return plugin.prefix
+}
+
+func (plugin *gateway) Middleware() []string {
+	return plugin.middleware
+}
@@ -0,0 +1,352 @@
+package provider_gateway_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// Module imports
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/provider-gateway"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
+	tokenauth_gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth-gateway"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+func Test_ProviderGateway_001(t *testing.T) {
+	task := taskConfig("task")
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create tasks and add them to the provider
+	if _, err := provider.New(ctx, gateway.Config{}); err == nil {
+		t.Error("Expected error without a router")
+	}
+	if _, err := provider.New(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, gateway.Config{Router: types.Task{Task: router}, Middleware: []string{}}); err == nil {
+		t.Error("Expected error without middleware")
+	}
+	gw, err := provider.New(ctx, gateway.Config{Router: types.Task{Task: router}, Middleware: authMiddleware(t, router)})
+	if err != nil {
+		t.Fatal(err)
+	} else {
+		t.Log(gw)
+	}
+
+	// Run the provider so that tasks emit events
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go provider.Run(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	// Make a request and decode the response
+	prefix := gw.(Gateway).Prefix()
+	request := func(t *testing.T, path string, code int, v any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, prefix+path, nil))
+		if status := w.Result().StatusCode; status != code {
+			t.Fatalf("%v: unexpected status code %v: %v", path, status, w.Body.String())
+		} else if v != nil {
+			if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("List", func(t *testing.T) {
+		var tasks []gateway.TaskResponse
+		request(t, "/tasks", http.StatusOK, &tasks)
+		if len(tasks) != 3 || tasks[0].Key != "task.task" || tasks[0].State != "running" {
+			t.Error("Unexpected response: ", tasks)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		var task gateway.TaskResponse
+		request(t, "/tasks/task.task", http.StatusOK, &task)
+		if task.Name != "task" || task.Label != "task" || len(task.Events) != 1 || task.Events[0].Key != "start" {
+			t.Error("Unexpected response: ", task)
+		}
+		request(t, "/tasks/task.missing", http.StatusNotFound, nil)
+	})
+}
+
+func Test_ProviderGateway_002(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create a router and gateway with a short heartbeat
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw, err := provider.New(ctx, gateway.Config{Router: types.Task{Task: router}, Middleware: authMiddleware(t, router), Heartbeat: types.Duration(10 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the provider and a server
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go provider.Run(ctx)
+	server := httptest.NewServer(router.(http.Handler))
+	defer server.Close()
+
+	// Stream events for a single task
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+gw.(Gateway).Prefix()+"/events?key=task.bb", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if ct := response.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatal("Unexpected content type", ct)
+	}
+
+	// Create tasks whilst streaming, after a heartbeat
+	time.Sleep(50 * time.Millisecond)
+	for _, label := range []string{"aa", "bb"} {
+		if _, err := provider.New(context.Background(), taskConfig(label)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Read a heartbeat, and the start events for task bb
+	var heartbeat bool
+	var events []gateway.EventResponse
+	scanner := bufio.NewScanner(response.Body)
+	for len(events) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, ":") {
+			heartbeat = true
+		} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var evt gateway.EventResponse
+			if err := json.Unmarshal([]byte(data), &evt); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, evt)
+		}
+	}
+	if !heartbeat {
+		t.Error("Expected heartbeat")
+	}
+	if len(events) != 2 || events[0].Task != "task.bb" || events[0].Key != "ProviderTaskStart" || events[1].Task != "task.bb" || events[1].Key != "start" {
+		t.Error("Unexpected events", events)
+	}
+}
+
+func Test_ProviderGateway_003(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create token authentication, and a gateway which requires the admin token
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := provider.New(ctx, tokenauth.Config{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, tokenauth_gateway.Config{Router: types.Task{Task: router}, Auth: types.Task{Task: auth}}); err != nil {
+		t.Fatal(err)
+	}
+	gw, err := provider.New(ctx, gateway.Config{Router: types.Task{Task: router}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the provider, and wait for it to stop before the folder is removed
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer func() { cancel(); <-done }()
+	go func() { defer close(done); provider.Run(ctx) }()
+	time.Sleep(200 * time.Millisecond)
+
+	// Create and match a token, and rotate the admin token
+	value, err := auth.(TokenAuth).Create("test", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.(TokenAuth).Matches(value)
+	admin, err := auth.(TokenAuth).Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Make a request with a token
+	prefix := gw.(Gateway).Prefix()
+	request := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, prefix+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.(http.Handler).ServeHTTP(w, req)
+		return w
+	}
+
+	// Requests without the admin token are refused
+	if w := request("/tasks", ""); w.Code != http.StatusUnauthorized {
+		t.Error("Unexpected status code", w.Code)
+	}
+	if w := request("/tasks", value); w.Code != http.StatusForbidden {
+		t.Error("Unexpected status code", w.Code)
+	}
+
+	// Token values are never returned
+	w := request("/tasks/tokenauth.tokenauth", admin)
+	if w.Code != http.StatusOK {
+		t.Fatal("Unexpected status code", w.Code)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, value) || strings.Contains(body, admin) {
+		t.Error("Unexpected token value in response", body)
+	}
+	var task gateway.TaskResponse
+	if err := json.Unmarshal([]byte(body), &task); err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]bool)
+	for _, evt := range task.Events {
+		keys[evt.Key] = true
+		if evt.Value != "" {
+			t.Error("Unexpected value for event", evt)
+		}
+	}
+	if !keys["TokenMatch"] || !keys["TokenRotated"] {
+		t.Error("Unexpected events", task.Events)
+	}
+}
+
+func Test_ProviderGateway_004(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+
+	// Create token authentication, and a gateway which requires the admin token
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := provider.New(ctx, tokenauth.Config{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, tokenauth_gateway.Config{Router: types.Task{Task: router}, Auth: types.Task{Task: auth}}); err != nil {
+		t.Fatal(err)
+	}
+	gw, err := provider.New(ctx, gateway.Config{Router: types.Task{Task: router}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the provider and a server, and wait for the provider to stop before
+	// the folder is removed
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer func() { cancel(); <-done }()
+	go func() { defer close(done); provider.Run(ctx) }()
+	server := httptest.NewServer(router.(http.Handler))
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	// Rotate the admin token, so it can be used for the request
+	admin, err := auth.(TokenAuth).Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stream events for token authentication
+	stream := func(token string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+gw.(Gateway).Prefix()+"/events?key=tokenauth.tokenauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	if response := stream(""); response.StatusCode != http.StatusUnauthorized {
+		t.Error("Unexpected status code", response.StatusCode)
+	} else {
+		response.Body.Close()
+	}
+	response := stream(admin)
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatal("Unexpected status code", response.StatusCode)
+	}
+
+	// Create and match a token, and rotate the admin token whilst streaming
+	value, err := auth.(TokenAuth).Create("test", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.(TokenAuth).Matches(value)
+	rotated, err := auth.(TokenAuth).Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read events until the admin token is rotated, which should not contain
+	// any token values
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, value) || strings.Contains(line, admin) || strings.Contains(line, rotated) {
+			t.Fatal("Unexpected token value in event", line)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var evt gateway.EventResponse
+			if err := json.Unmarshal([]byte(data), &evt); err != nil {
+				t.Fatal(err)
+			} else if evt.Value != "" {
+				t.Error("Unexpected value for event", evt)
+			} else if evt.Key == "TokenRotated" {
+				break
+			}
+		}
+	}
+}
+
+func taskConfig(label string) provider.Config {
+	return provider.Config{Label_: label}
+}
+
+// authMiddleware adds middleware to the router which allows all requests,
+// and returns the name of the middleware
+func authMiddleware(t *testing.T, router Task) []string {
+	t.Helper()
+	if err := router.(Router).AddMiddleware("auth", func(fn http.HandlerFunc) http.HandlerFunc {
+		return fn
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"auth"}
+}
@@ -0,0 +1,114 @@
+package provider_gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	// Modules
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// TaskResponse is the response for a task
+type TaskResponse struct {
+	Key    string          `json:"key"`
+	Name   string          `json:"name"`
+	Label  string          `json:"label"`
+	State  string          `json:"state"`
+	Error  string          `json:"error,omitempty"`
+	Task   string          `json:"task"`
+	Events []EventResponse `json:"events,omitempty"`
+}
+
+// EventResponse is the response for an event emitted by a task. The task
+// key is only set for events on an event stream
+type EventResponse struct {
+	Task  string `json:"task,omitempty"`
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// HANDLERS
+
+func (plugin *gateway) ListHandler(w http.ResponseWriter, r *http.Request) {
+	tasks := plugin.provider.Tasks()
+
+	// Create response
+	result := make([]TaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, taskResponse(task, false))
+	}
+
+	// Serve response
+	util.ServeJSON(w, result, http.StatusOK, 2)
+}
+
+func (plugin *gateway) GetHandler(w http.ResponseWriter, r *http.Request) {
+	params := context.ReqParams(r)
+	if len(params) != 2 {
+		util.ServeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Get the task
+	key := params[0] + "." + params[1]
+	task := plugin.provider.Get(key)
+	if task == nil {
+		util.ServeError(w, http.StatusNotFound, fmt.Sprintf("%q", key))
+		return
+	}
+
+	// Serve response
+	util.ServeJSON(w, taskResponse(*task, true), http.StatusOK, 2)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// taskResponse returns the response for a task, optionally including
+// the recent events emitted by the task
+func taskResponse(task ProviderTask, events bool) TaskResponse {
+	name, label, _ := strings.Cut(task.Key, ".")
+	result := TaskResponse{
+		Key:   task.Key,
+		Name:  name,
+		Label: label,
+		State: task.State.String(),
+		Task:  fmt.Sprint(task.Task),
+	}
+	if task.Err != nil {
+		result.Error = task.Err.Error()
+	}
+	if events {
+		result.Events = make([]EventResponse, 0, len(task.Events))
+		for _, evt := range task.Events {
+			result.Events = append(result.Events, eventResponse(evt))
+		}
+	}
+	return result
+}
+
+// eventResponse returns the response for an event
+func eventResponse(evt Event) EventResponse {
+	var result EventResponse
+	if key := evt.Key(); key != nil {
+		result.Key = fmt.Sprint(key)
+	}
+	if value := evt.Value(); value != nil {
+		result.Value = fmt.Sprint(value)
+	}
+	if err := evt.Error(); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,25 @@
+package provider_gateway
+
+import (
+	"context"
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Run until done
+func (plugin *gateway) Run(ctx context.Context) error {
+	<-ctx.Done()
+	plugin.Emit(nil)
+	return ctx.Err()
+}
+
+func (plugin *gateway) Label() string {
+	return plugin.label
+}
+
+// Close removes the handlers from the router, when the task is removed
+// from the provider
+func (plugin *gateway) Close() error {
+	return plugin.router.RemoveHandler(plugin)
+}
@@ -22,7 +22,55 @@ tasks have been created, they can be run:
 	  }
 
 This will run all the tasks in the background, ensuring that any dependencies for tasks are satisfied
-when the context is cancelled.
+when the context is cancelled. Tasks are stopped in reverse dependency order, so a task is only
+cancelled once the tasks which depend on it have exited. Each task has a grace period (set with
+the Grace field) in which to exit, after which it is abandoned and a ProviderTaskTimeout event
+is emitted. A ProviderTaskExit event is emitted as each task exits.
+
+# Adding and removing tasks
+
+Tasks can be created whilst the provider is running, in which case they are started immediately and
+a ProviderTaskStart event is emitted. The Remove method cancels a single task, waits for it to exit
+within the grace period and unsubscribes from its events, after which a ProviderTaskRemoved event is
+emitted. Tasks created whilst creating the removed task are also removed, unless they are
+referenced by other tasks. A task which is referenced by another task cannot be removed.
+
+# Reloading configurations
+
+The Reload method compares a new set of configurations with the configurations used to create the
+existing tasks, keyed by name and label. Tasks whose configuration was removed or changed are
+removed, and new or changed configurations are created, together with any tasks which depend on a
+replaced task. Tasks whose configuration did not change keep running. A ProviderReload event is
+emitted with a Changes value which lists the tasks added, removed and replaced. If a task cannot
+be created, the tasks created by the reload are removed and the removed tasks are created again
+with their previous configuration, and an error is returned.
+
+A task which implements io.Closer is closed when it is removed, so a gateway can remove its
+handlers and middleware from a router before it is replaced.
+
+# Supervision
+
+By default, a task which exits is not restarted. The Policy field sets the default supervision
+policy for tasks, and SetPolicy sets the policy for an individual task. A configuration with a
+field of type *types.Policy (a "policy" block in HCL) sets the policy for its task when the task is
+created, where attributes which are not set are taken from the default policy. A task can be restarted
+never, on failure (when the task returns an error) or always, with an exponential backoff between
+restarts and an optional maximum number of restarts. A ProviderTaskRestart event is emitted before
+each restart.
+
+# Introspection
+
+The Tasks method returns the tasks in the order they were created, and the Get method returns a
+single task by key. Each task is described with its state (created, running, restarting, failed or
+stopped), the error when the task last failed, and a ring buffer of the most recent events emitted
+by the task. The Events field sets the number of events retained for each task.
+
+The SubTasks method returns a buffered channel on which the events from all tasks are received,
+where the key of each event is the task key and the value is the event emitted. Events are dropped
+when the channel is full, so slow subscribers do not block the provider. UnsubTasks closes the
+channel. The ObserveTasks method calls a function for every event instead, which is used where
+no events can be dropped, such as when counting events. The function is called by the task which
+emitted the event, so it should return quickly.
 
 # Events
 
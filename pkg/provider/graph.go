@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	typeTask     = reflect.TypeOf(types.Task{})
+	typeListTask = reflect.TypeOf([]types.Task{})
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NewGraph creates tasks from configurations which may reference each other,
+// or tasks which have already been created. The tasks are created in
+// dependency order, and returned in the same order
+func (p *provider) NewGraph(ctx context.Context, configs ...iface.TaskPlugin) ([]iface.Task, error) {
+	ordered, err := p.Order(configs...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create tasks, resolving references as we go
+	result := make([]iface.Task, 0, len(ordered))
+	for _, config := range ordered {
+		if task, err := p.New(ctx, config); err != nil {
+			return result, fmt.Errorf("%v: %w", keyForConfig(config), err)
+		} else {
+			result = append(result, task)
+		}
+	}
+
+	// Return success
+	return result, nil
+}
+
+// Order returns configurations so that each configuration follows the
+// configurations it references. Otherwise, the order is preserved. An error
+// is returned for duplicate configurations, references which cannot be
+// resolved and circular references
+func (p *provider) Order(configs ...iface.TaskPlugin) ([]iface.TaskPlugin, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return order(configs, func(key string) bool {
+		_, exists := p.tasks[key]
+		return exists
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// order returns configurations in dependency order, where exists returns
+// true for tasks which have already been created
+func order(configs []iface.TaskPlugin, exists func(string) bool) ([]iface.TaskPlugin, error) {
+	// Index configurations by name.label
+	keys := make(map[string]int, len(configs))
+	for i, config := range configs {
+		key := keyForConfig(config)
+		if _, defined := keys[key]; defined {
+			return nil, ErrDuplicateEntry.Withf("Resource %q defined more than once", key)
+		} else if exists(key) {
+			return nil, ErrDuplicateEntry.Withf("Resource %q already exists", key)
+		}
+		keys[key] = i
+	}
+
+	// Create the edges, checking for dangling references
+	deps := make([][]int, len(configs))
+	for i, config := range configs {
+		for _, ref := range refsForConfig(config) {
+			if j, defined := keys[ref.Ref]; defined {
+				deps[i] = append(deps[i], j)
+			} else if !exists(ref.Ref) && ref.Task == nil {
+				return nil, ErrNotFound.Withf("%v: unresolved reference %q", keyForConfig(config), ref.Ref)
+			}
+		}
+	}
+
+	// Repeatedly append the first configuration with all dependencies appended
+	result := make([]iface.TaskPlugin, 0, len(configs))
+	done := make([]bool, len(configs))
+	for len(result) < len(configs) {
+		next := -1
+		for i := range configs {
+			if !done[i] && allDone(deps[i], done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, ErrBadParameter.Withf("Circular reference: %v", cycle(configs, deps, done))
+		}
+		done[next] = true
+		result = append(result, configs[next])
+	}
+
+	// Return success
+	return result, nil
+}
+
+// resolve sets each reference in a configuration to a task which has
+// already been created, and returns the configuration and the keys of the
+// referenced tasks. Where the configuration is not a pointer, a copy of the
+// configuration is returned
+func (p *provider) resolve(config iface.TaskPlugin) (iface.TaskPlugin, []string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var deps []string
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	for _, ref := range refsForValue(v) {
+		if ref.Task != nil {
+			if key := p.keyForTask(ref.Task); key != "" {
+				deps = append(deps, key)
+			}
+		} else if task, exists := p.tasks[ref.Ref]; !exists {
+			return nil, nil, ErrNotFound.Withf("%v: unresolved reference %q", keyForConfig(config), ref.Ref)
+		} else {
+			ref.Task = task
+			deps = append(deps, ref.Ref)
+		}
+	}
+	if reflect.ValueOf(config).Kind() != reflect.Ptr {
+		return v.Elem().Interface().(iface.TaskPlugin), deps, nil
+	} else {
+		return config, deps, nil
+	}
+}
+
+// unresolve returns a configuration where references to other tasks by key
+// are cleared, so they are resolved again when a task is created
+func unresolve(config iface.TaskPlugin) iface.TaskPlugin {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	for _, ref := range refsForValue(v) {
+		if ref.Ref != "" {
+			ref.Task = nil
+		}
+	}
+	if reflect.ValueOf(config).Kind() != reflect.Ptr {
+		return v.Elem().Interface().(iface.TaskPlugin)
+	} else {
+		return config
+	}
+}
+
+// keyForTask returns the key for a task, or an empty string if the task
+// was not created by the provider. The provider lock is held by the caller
+func (p *provider) keyForTask(task iface.Task) string {
+	for key, t := range p.tasks {
+		if t == task {
+			return key
+		}
+	}
+	return ""
+}
+
+// keyForConfig returns the unique key for a configuration
+func keyForConfig(config iface.TaskPlugin) string {
+	return config.Name() + "." + config.Label()
+}
+
+// refsForConfig returns the references in a configuration
+func refsForConfig(config iface.TaskPlugin) []*types.Task {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	return refsForValue(v)
+}
+
+// refsForValue returns the references in a pointer to a struct
+func refsForValue(v reflect.Value) []*types.Task {
+	var result []*types.Task
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch field.Type() {
+		case typeTask:
+			if ref := field.Addr().Interface().(*types.Task); ref.Ref != "" {
+				result = append(result, ref)
+			}
+		case typeListTask:
+			for j := 0; j < field.Len(); j++ {
+				if ref := field.Index(j).Addr().Interface().(*types.Task); ref.Ref != "" {
+					result = append(result, ref)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// allDone returns true if all dependencies are done
+func allDone(deps []int, done []bool) bool {
+	for _, j := range deps {
+		if !done[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycle returns a description of a circular reference. Every configuration
+// which is not done depends on another which is not done, so following
+// the dependencies will eventually revisit a configuration
+func cycle(configs []iface.TaskPlugin, deps [][]int, done []bool) string {
+	var path []int
+	seen := make(map[int]int)
+	for i := range configs {
+		if done[i] {
+			continue
+		}
+		for {
+			if j, exists := seen[i]; exists {
+				path = append(path[j:], i)
+				break
+			}
+			seen[i] = len(path)
+			path = append(path, i)
+			for _, j := range deps[i] {
+				if !done[j] {
+					i = j
+					break
+				}
+			}
+		}
+		break
+	}
+	keys := make([]string, 0, len(path))
+	for _, i := range path {
+		keys = append(keys, keyForConfig(configs[i]))
+	}
+	return strings.Join(keys, " -> ")
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Restart determines when a task is restarted after it exits
+type Restart uint
+
+// Policy determines how a task is supervised
+type Policy struct {
+	Restart     Restart       // When to restart the task
+	MaxRestarts uint          // Maximum number of restarts, or zero for no limit
+	Backoff     time.Duration // Delay before the first restart, which doubles for each restart
+	MaxBackoff  time.Duration // Maximum delay before a restart
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	RestartNever     Restart = iota // Never restart the task
+	RestartOnFailure                // Restart the task when it exits with an error
+	RestartAlways                   // Restart the task whenever it exits
+)
+
+var (
+	typePolicy = reflect.TypeOf((*types.Policy)(nil))
+)
+
+const (
+	DefaultBackoff    = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// ParseRestart returns a restart policy from a string, which is
+// one of "never", "on-failure" or "always"
+func ParseRestart(v string) (Restart, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "never", "":
+		return RestartNever, nil
+	case "on-failure":
+		return RestartOnFailure, nil
+	case "always":
+		return RestartAlways, nil
+	default:
+		return RestartNever, ErrBadParameter.Withf("invalid restart policy %q", v)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (v Restart) String() string {
+	switch v {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	default:
+		return "[?? Invalid Restart value]"
+	}
+}
+
+func (p Policy) String() string {
+	str := "<policy"
+	str += fmt.Sprintf(" restart=%v", p.Restart)
+	if p.MaxRestarts > 0 {
+		str += fmt.Sprintf(" max_restarts=%v", p.MaxRestarts)
+	}
+	if p.Backoff > 0 {
+		str += fmt.Sprintf(" backoff=%v", p.Backoff)
+	}
+	if p.MaxBackoff > 0 {
+		str += fmt.Sprintf(" max_backoff=%v", p.MaxBackoff)
+	}
+	return str + ">"
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// restart returns true if a task should be restarted, given the number
+// of restarts so far and whether the task failed
+func (p Policy) restart(restarts uint, failed bool) bool {
+	if p.MaxRestarts > 0 && restarts >= p.MaxRestarts {
+		return false
+	}
+	switch p.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return failed
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before a restart, given the number of restarts
+// so far
+func (p Policy) backoff(restarts uint) time.Duration {
+	delay, max := p.Backoff, p.MaxBackoff
+	if delay <= 0 {
+		delay = DefaultBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	for i := uint(0); i < restarts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// policyForConfig returns the supervision policy set by a policy block in
+// a configuration, where attributes which are not set are taken from the
+// default policy. Returns nil if the configuration does not set a policy
+func policyForConfig(config iface.TaskPlugin, policy Policy) (*Policy, error) {
+	v := reflect.Indirect(reflect.ValueOf(config))
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Type() != typePolicy || field.IsNil() {
+			continue
+		}
+		config := field.Interface().(*types.Policy)
+		if config.Restart != "" {
+			if restart, err := ParseRestart(config.Restart); err != nil {
+				return nil, err
+			} else {
+				policy.Restart = restart
+			}
+		}
+		if config.MaxRestarts > 0 {
+			policy.MaxRestarts = config.MaxRestarts
+		}
+		if config.Backoff < 0 {
+			return nil, ErrBadParameter.Withf("invalid backoff %v", time.Duration(config.Backoff))
+		} else if config.Backoff > 0 {
+			policy.Backoff = time.Duration(config.Backoff)
+		}
+		return &policy, nil
+	}
+	return nil, nil
+}
@@ -2,16 +2,17 @@ package provider
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 
 	// Module imports
-	multierror "github.com/hashicorp/go-multierror"
 	iface "github.com/mutablelogic/terraform-provider-nginx"
+	taskcontext "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
 	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
 
 	// Namespace imports
 	. "github.com/djthorpe/go-errors"
@@ -23,21 +24,89 @@ import (
 type provider struct {
 	event.PubSub
 
+	// Grace period for each task to exit when the provider is stopping
+	Grace time.Duration
+
+	// Default supervision policy for tasks
+	Policy Policy
+
+	// Number of recent events retained for each task
+	Events uint
+
+	// Capacity of the channels returned by SubTasks
+	StreamCap uint
+
+	// Supervision policy for each task, keyed by label
+	policies map[string]Policy
+
 	// Enumeration of task plugins, keyed by name
 	plugins map[string]reflect.Type
 
 	// Enumeration of tasks, keyed by label
 	tasks map[string]iface.Task
+
+	// Task keys in the order the tasks were created
+	order []string
+
+	// Dependencies for each task, keyed by label
+	deps map[string][]string
+
+	// Tasks currently being created, which depend on any tasks created
+	// whilst they are being created
+	creating []string
+
+	// Tasks created whilst creating another task, keyed by label
+	children map[string][]string
+
+	// Configurations for tasks which were not created whilst creating
+	// another task, keyed by label
+	configs map[string]iface.TaskPlugin
+
+	// State and recent events for each task, keyed by label
+	status map[string]*status
+
+	// Channels returned by SubTasks
+	streams []chan iface.Event
+
+	// Functions called for every event from tasks
+	observers []*observer
+
+	// Guards the tasks whilst the provider is running
+	mu sync.Mutex
+
+	// Set whilst the provider is running
+	ctx     context.Context
+	running map[string]*runner
+	active  *sync.Cond
+	result  error
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DefaultGrace     = 10 * time.Second
+	DefaultEvents    = 10
+	DefaultStreamCap = 100
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
 // New creates a new empty provider with no tasks
 func New() *provider {
 	p := new(provider)
+	p.Grace = DefaultGrace
+	p.Events = DefaultEvents
+	p.StreamCap = DefaultStreamCap
 	p.plugins = make(map[string]reflect.Type)
 	p.tasks = make(map[string]iface.Task)
+	p.deps = make(map[string][]string)
+	p.policies = make(map[string]Policy)
+	p.children = make(map[string][]string)
+	p.configs = make(map[string]iface.TaskPlugin)
+	p.status = make(map[string]*status)
+	p.active = sync.NewCond(&p.mu)
 	return p
 }
 
@@ -48,66 +117,36 @@ func (p *provider) Label() string {
 	return "provider"
 }
 
-func (p *provider) Run(ctx context.Context) error {
-	var wg sync.WaitGroup
-	var result error
-
-	// Run all tasks
-	for label, task := range p.tasks {
-		wg.Add(2)
-
-		// Emit events from task
-		go func(task iface.Task) {
-			defer wg.Done()
-			ch := task.Sub()
-			if ch != nil {
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case event := <-ch:
-						if event != nil && !p.Emit(event) {
-							panic(fmt.Sprintln("Unable to emit: ", event))
-						}
-					}
-				}
-			}
-		}(task)
-
-		// Run task
-		go func(label string, task iface.Task) {
-			defer wg.Done()
-			if err := task.Run(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
-				result = multierror.Append(result, fmt.Errorf("%v: %w", label, err))
-			}
-		}(label, task)
+// SetPolicy sets the supervision policy for a task, which overrides
+// the default policy
+func (p *provider) SetPolicy(key string, policy Policy) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.tasks[key]; !exists {
+		return ErrNotFound.Withf("Resource %q not found", key)
 	}
-
-	// Wait until all tasks are completed
-	wg.Wait()
-
-	// TODO: Close tasks in the reverse order they were created
-
-	// Close channel
-	p.Emit(nil)
-
-	// Return any errors
-	return result
+	p.policies[key] = policy
+	return nil
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
 func (p *provider) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	str := "<provider"
 	str += fmt.Sprintf(" label=%q", p.Label())
-	for label, task := range p.tasks {
-		str += fmt.Sprintf(" %v=%v", label, task)
+	for _, key := range p.order {
+		str += fmt.Sprintf(" %v=%v", key, p.tasks[key])
 	}
 	return str + ">"
 }
 
-// New creates a new task from a configuration with a unique label
+// New creates a new task from a configuration with a unique label. Any
+// references to other tasks in the configuration are resolved first. If the
+// provider is running, the task is started immediately. New should not be
+// called concurrently from more than one goroutine
 func (p *provider) New(ctx context.Context, config iface.TaskPlugin) (iface.Task, error) {
 	name := config.Name()
 
@@ -129,20 +168,63 @@ func (p *provider) New(ctx context.Context, config iface.TaskPlugin) (iface.Task
 		return nil, ErrBadParameter.Withf("Invalid label %q for task %q ", label, name)
 	}
 
-	// Create a new task
-	task, err := config.New(ctx, p)
+	// Check for existing task
+	key := name + "." + label
+	p.mu.Lock()
+	_, exists := p.tasks[key]
+	p.mu.Unlock()
+	if exists {
+		return nil, ErrDuplicateEntry.Withf("Resource %q already exists", key)
+	}
+
+	// Resolve references to other tasks
+	config, deps, err := p.resolve(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Obtain the supervision policy from the configuration
+	policy, err := policyForConfig(config, p.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", key, err)
+	}
+
+	// Create a new task with the name and label in the context, recording any
+	// tasks created whilst creating this task as dependencies
+	p.creating = append(p.creating, key)
+	task, err := config.New(taskcontext.WithNameLabel(ctx, name, label), p)
+	p.creating = p.creating[:len(p.creating)-1]
 	if err != nil {
 		return nil, err
 	} else if task == nil {
 		return nil, ErrInternalAppError.Withf("Unexpected nil return when creating task %q ", name)
 	}
 
-	// Add resource to map
-	key := name + "." + label
-	if _, exists := p.tasks[key]; exists {
-		return nil, ErrDuplicateEntry.Withf("Resource %q already exists", key)
+	// Add resource to map, and start the task if the provider is running
+	p.mu.Lock()
+	p.tasks[key] = task
+	p.status[key] = &status{state: plugin.TaskCreated}
+	p.order = append(p.order, key)
+	p.deps[key] = append(p.deps[key], deps...)
+	if policy != nil {
+		p.policies[key] = *policy
+	}
+	if n := len(p.creating); n > 0 {
+		parent := p.creating[n-1]
+		p.deps[parent] = append(p.deps[parent], key)
+		p.children[parent] = append(p.children[parent], key)
 	} else {
-		p.tasks[key] = task
+		p.configs[key] = config
+	}
+	running := p.running != nil
+	if running {
+		p.start(key)
+	}
+	p.mu.Unlock()
+
+	// Emit an event when a task is started
+	if running {
+		p.Emit(event.NewEvent(plugin.ProviderTaskStart, key))
 	}
 
 	// Return success
@@ -3,16 +3,134 @@ package provider_test
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	accesslog "github.com/mutablelogic/terraform-provider-nginx/pkg/accesslog"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	logger "github.com/mutablelogic/terraform-provider-nginx/pkg/logger"
+	metrics "github.com/mutablelogic/terraform-provider-nginx/pkg/metrics"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
 
 	// Namespace imports
 	. "github.com/djthorpe/go-errors"
 	. "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
 )
 
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RefConfig is a task configuration which references other tasks
+type RefConfig struct {
+	Label_ string
+	Refs   []types.Task
+}
+
+func (c RefConfig) Name() string {
+	return "ref"
+}
+
+func (c RefConfig) Label() string {
+	return c.Label_
+}
+
+func (c RefConfig) New(ctx context.Context, provider iface.Provider) (iface.Task, error) {
+	for _, ref := range c.Refs {
+		if ref.Task == nil {
+			return nil, ErrInternalAppError.With("unresolved reference: ", ref.Ref)
+		}
+	}
+	return Config{Label_: c.Label_}.New(ctx, provider)
+}
+
+// StopConfig is a task configuration which records when the task exits,
+// and which can ignore cancellation
+type StopConfig struct {
+	Label_ string
+	Refs   []types.Task
+	Hang   bool
+	Exited chan<- string
+}
+
+type StopTask struct {
+	event.PubSub
+	StopConfig
+}
+
+func (c StopConfig) Name() string {
+	return "stop"
+}
+
+func (c StopConfig) Label() string {
+	return c.Label_
+}
+
+func (c StopConfig) New(context.Context, iface.Provider) (iface.Task, error) {
+	return &StopTask{StopConfig: c}, nil
+}
+
+func (t *StopTask) Run(ctx context.Context) error {
+	<-ctx.Done()
+	if t.Hang {
+		select {}
+	}
+	time.Sleep(10 * time.Millisecond)
+	t.Exited <- t.Label_
+	return nil
+}
+
+// FailConfig is a task configuration for a task which fails a number of
+// times before running until cancelled
+type FailConfig struct {
+	Label_ string
+	Fail   int
+	Policy *types.Policy
+}
+
+type FailTask struct {
+	event.PubSub
+	fail int
+	runs int
+}
+
+func (c FailConfig) Name() string {
+	return "fail"
+}
+
+func (c FailConfig) Label() string {
+	return c.Label_
+}
+
+func (c FailConfig) New(context.Context, iface.Provider) (iface.Task, error) {
+	return &FailTask{fail: c.Fail}, nil
+}
+
+func (t *FailTask) Run(ctx context.Context) error {
+	t.runs++
+	if t.runs <= t.fail {
+		return ErrUnexpectedResponse.With("run ", t.runs)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func Ref(label string, refs ...string) *RefConfig {
+	c := &RefConfig{Label_: label}
+	for _, ref := range refs {
+		c.Refs = append(c.Refs, types.Task{Ref: "ref." + ref})
+	}
+	return c
+}
+
 /////////////////////////////////////////////////////////////////////
 // TESTS
 
@@ -83,3 +201,584 @@ func Test_Provider_005(t *testing.T) {
 		t.Fatal("Expected success, got:", err)
 	}
 }
+
+func Test_Provider_006(t *testing.T) {
+	// Tasks are created in dependency order, with references resolved
+	provider := New()
+	a, b, c := Ref("aa", "bb", "cc"), Ref("bb", "cc"), Ref("cc")
+	tasks, err := provider.NewGraph(context.Background(), a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tasks) != 3 {
+		t.Fatal("Unexpected number of tasks returned")
+	}
+	if a.Refs[0].Task != tasks[1] || a.Refs[1].Task != tasks[0] || b.Refs[0].Task != tasks[0] {
+		t.Error("Unexpected references")
+	}
+
+	// References to existing tasks are resolved
+	if _, err := provider.New(context.Background(), Ref("dd", "aa")); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Provider_007(t *testing.T) {
+	// Dangling references
+	provider := New()
+	if _, err := provider.NewGraph(context.Background(), Ref("aa", "bb")); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got:", err)
+	}
+	if _, err := provider.New(context.Background(), Ref("aa", "bb")); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got:", err)
+	}
+	if _, err := New().New(context.Background(), *Ref("aa", "bb")); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got:", err)
+	}
+}
+
+func Test_Provider_008(t *testing.T) {
+	// Circular references
+	provider := New()
+	tests := [][]iface.TaskPlugin{
+		{Ref("aa", "aa")},
+		{Ref("aa", "bb"), Ref("bb", "aa")},
+		{Ref("aa", "bb"), Ref("bb", "cc"), Ref("cc", "bb"), Ref("dd")},
+	}
+	for i, test := range tests {
+		if _, err := provider.NewGraph(context.Background(), test...); !errors.Is(err, ErrBadParameter) {
+			t.Error(i, "Expected ErrBadParameter, got:", err)
+		} else {
+			t.Log(err)
+		}
+	}
+
+	// Duplicate configurations
+	if _, err := provider.NewGraph(context.Background(), Ref("aa"), Ref("aa")); !errors.Is(err, ErrDuplicateEntry) {
+		t.Error("Expected ErrDuplicateEntry, got:", err)
+	}
+}
+
+func Test_Provider_009(t *testing.T) {
+	// Tasks are stopped in reverse dependency order
+	provider := New()
+	exited := make(chan string, 3)
+	stop := func(label string, refs ...string) *StopConfig {
+		c := &StopConfig{Label_: label, Exited: exited}
+		for _, ref := range refs {
+			c.Refs = append(c.Refs, types.Task{Ref: "stop." + ref})
+		}
+		return c
+	}
+	if _, err := provider.NewGraph(context.Background(), stop("aa", "bb"), stop("bb", "cc"), stop("cc")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect exit events
+	var events []any
+	ch := provider.Sub()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for evt := range ch {
+			if evt.Key() == plugin.ProviderTaskExit {
+				events = append(events, evt.Value())
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := provider.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	close(exited)
+
+	// Check order
+	var order []string
+	for label := range exited {
+		order = append(order, label)
+	}
+	if len(order) != 3 || order[0] != "aa" || order[1] != "bb" || order[2] != "cc" {
+		t.Error("Unexpected stop order", order)
+	}
+	if len(events) != 3 || events[0] != "stop.aa" || events[2] != "stop.cc" {
+		t.Error("Unexpected exit events", events)
+	}
+}
+
+func Test_Provider_010(t *testing.T) {
+	// Tasks which do not exit within the grace period are abandoned
+	provider := New()
+	provider.Grace = 100 * time.Millisecond
+	exited := make(chan string, 2)
+	if _, err := provider.New(context.Background(), &StopConfig{Label_: "aa", Hang: true, Exited: exited}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(context.Background(), &StopConfig{Label_: "bb", Refs: []types.Task{{Ref: "stop.aa"}}, Exited: exited}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect timeout events
+	var events []any
+	ch := provider.Sub()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for evt := range ch {
+			if evt.Key() == plugin.ProviderTaskTimeout {
+				events = append(events, evt.Value())
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	now := time.Now()
+	if err := provider.Run(ctx); !errors.Is(err, ErrInternalAppError) {
+		t.Error("Expected ErrInternalAppError, got:", err)
+	} else if since := time.Since(now); since > time.Second {
+		t.Error("Unexpected time to stop", since)
+	}
+	<-done
+	if len(events) != 1 || events[0] != "stop.aa" {
+		t.Error("Unexpected timeout events", events)
+	}
+}
+
+func Test_Provider_011(t *testing.T) {
+	var tests = []struct {
+		Policy   Policy
+		Fail     int
+		Restarts int
+		Err      bool
+	}{
+		{Policy{}, 0, 0, false},
+		{Policy{}, 1, 0, true},
+		{Policy{Restart: RestartOnFailure, Backoff: time.Millisecond}, 3, 3, false},
+		{Policy{Restart: RestartOnFailure, Backoff: time.Millisecond, MaxRestarts: 2}, 3, 2, true},
+		{Policy{Restart: RestartAlways, Backoff: time.Millisecond, MaxRestarts: 5}, 3, 3, false},
+	}
+	for i, test := range tests {
+		provider := New()
+		if _, err := provider.New(context.Background(), FailConfig{Label_: "test", Fail: test.Fail}); err != nil {
+			t.Fatal(err)
+		} else if err := provider.SetPolicy("fail.test", test.Policy); err != nil {
+			t.Fatal(err)
+		}
+
+		// Count restart events
+		var restarts int
+		ch := provider.Sub()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for evt := range ch {
+				if evt.Key() == plugin.ProviderTaskRestart {
+					restarts++
+				}
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		err := provider.Run(ctx)
+		cancel()
+		<-done
+		if test.Err && !errors.Is(err, ErrUnexpectedResponse) {
+			t.Error(i, "Expected error, got:", err)
+		} else if !test.Err && err != nil {
+			t.Error(i, "Unexpected error:", err)
+		}
+		if restarts != test.Restarts {
+			t.Error(i, "Unexpected number of restarts:", restarts)
+		}
+	}
+}
+
+func Test_Provider_012(t *testing.T) {
+	// Restart policies
+	for _, v := range []Restart{RestartNever, RestartOnFailure, RestartAlways} {
+		if r, err := ParseRestart(v.String()); err != nil {
+			t.Error(err)
+		} else if r != v {
+			t.Error("Unexpected restart policy", r)
+		}
+	}
+	if _, err := ParseRestart("sometimes"); !errors.Is(err, ErrBadParameter) {
+		t.Error("Expected ErrBadParameter, got:", err)
+	}
+
+	// Unknown task
+	if err := New().SetPolicy("fail.test", Policy{}); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got:", err)
+	}
+}
+
+func Test_Provider_013(t *testing.T) {
+	// Tasks are started when created whilst running, and stopped when removed
+	provider := New()
+	exited := make(chan string, 2)
+	if _, err := provider.New(context.Background(), &StopConfig{Label_: "aa", Exited: exited}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect start and removed events
+	var events []string
+	ch := provider.Sub()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for evt := range ch {
+			switch evt.Key() {
+			case plugin.ProviderTaskStart, plugin.ProviderTaskRemoved:
+				events = append(events, evt.Key().(plugin.ProviderEventType).String()+" "+evt.Value().(string))
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- provider.Run(ctx)
+	}()
+
+	// Add a task whilst running
+	time.Sleep(10 * time.Millisecond)
+	if _, err := provider.New(context.Background(), &StopConfig{Label_: "bb", Exited: exited}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the task, which waits for the task to exit
+	if err := provider.Remove("stop.bb"); err != nil {
+		t.Fatal(err)
+	} else if label := <-exited; label != "bb" {
+		t.Error("Unexpected task exited", label)
+	}
+
+	// Create the task again
+	if _, err := provider.New(context.Background(), &StopConfig{Label_: "bb", Exited: exited}); err != nil {
+		t.Error(err)
+	}
+
+	// Stop the provider
+	cancel()
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	<-done
+	if len(events) != 3 || events[0] != "ProviderTaskStart stop.bb" || events[1] != "ProviderTaskRemoved stop.bb" || events[2] != "ProviderTaskStart stop.bb" {
+		t.Error("Unexpected events", events)
+	}
+}
+
+func Test_Provider_014(t *testing.T) {
+	provider := New()
+	if _, err := provider.NewGraph(context.Background(), Ref("aa"), Ref("bb", "aa")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown task
+	if err := provider.Remove("ref.cc"); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got:", err)
+	}
+
+	// Referenced task
+	if err := provider.Remove("ref.aa"); !errors.Is(err, ErrBadParameter) {
+		t.Error("Expected ErrBadParameter, got:", err)
+	}
+
+	// Remove tasks when not running
+	if err := provider.Remove("ref.bb"); err != nil {
+		t.Error(err)
+	} else if err := provider.Remove("ref.aa"); err != nil {
+		t.Error(err)
+	} else if _, err := provider.New(context.Background(), Ref("aa")); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Provider_015(t *testing.T) {
+	provider := New()
+	if _, err := provider.NewGraph(context.Background(), Ref("aa"), Ref("bb", "aa"), Ref("cc")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged configurations do not change any tasks
+	if changes, err := provider.Reload(context.Background(), Ref("aa"), Ref("bb", "aa"), Ref("cc")); err != nil {
+		t.Error(err)
+	} else if len(changes.Added) != 0 || len(changes.Removed) != 0 || len(changes.Replaced) != 0 {
+		t.Error("Unexpected changes", changes)
+	}
+
+	// Add, remove and replace tasks
+	if changes, err := provider.Reload(context.Background(), Ref("dd", "aa"), Ref("aa"), Ref("bb")); err != nil {
+		t.Error(err)
+	} else if len(changes.Added) != 1 || changes.Added[0] != "ref.dd" {
+		t.Error("Unexpected added tasks", changes)
+	} else if len(changes.Removed) != 1 || changes.Removed[0] != "ref.cc" {
+		t.Error("Unexpected removed tasks", changes)
+	} else if len(changes.Replaced) != 1 || changes.Replaced[0] != "ref.bb" {
+		t.Error("Unexpected replaced tasks", changes)
+	}
+
+	// Tasks which depend on a replaced task are also replaced
+	if changes, err := provider.Reload(context.Background(), Ref("dd", "aa"), Ref("aa", "bb"), Ref("bb")); err != nil {
+		t.Error(err)
+	} else if len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Error("Unexpected changes", changes)
+	} else if len(changes.Replaced) != 2 || changes.Replaced[0] != "ref.dd" || changes.Replaced[1] != "ref.aa" {
+		t.Error("Unexpected replaced tasks", changes)
+	}
+
+	// Unresolved references are an error
+	if _, err := provider.Reload(context.Background(), Ref("aa", "ee")); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got:", err)
+	}
+}
+
+func Test_Provider_016(t *testing.T) {
+	// Tasks with unchanged configurations keep running on reload
+	provider := New()
+	exited := make(chan string, 2)
+	aa := &StopConfig{Label_: "aa", Exited: exited}
+	bb := &StopConfig{Label_: "bb", Exited: exited}
+	if _, err := provider.NewGraph(context.Background(), aa, bb); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect reload events
+	var events []string
+	ch := provider.Sub()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for evt := range ch {
+			if evt.Key() == plugin.ProviderReload {
+				events = append(events, evt.Value().(Changes).String())
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- provider.Run(ctx)
+	}()
+
+	// Replace task bb, which waits for the task to exit
+	time.Sleep(10 * time.Millisecond)
+	replaced := make(chan string, 1)
+	if _, err := provider.Reload(context.Background(), &StopConfig{Label_: "aa", Exited: exited}, &StopConfig{Label_: "bb", Exited: replaced}); err != nil {
+		t.Fatal(err)
+	} else if label := <-exited; label != "bb" {
+		t.Error("Unexpected task exited", label)
+	}
+	select {
+	case label := <-exited:
+		t.Error("Unexpected task exited", label)
+	case <-time.After(50 * time.Millisecond):
+		// Task aa is still running
+	}
+
+	// Stop the provider
+	cancel()
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	<-done
+	if len(events) != 1 || events[0] != `<changes replaced=["stop.bb"]>` {
+		t.Error("Unexpected events", events)
+	}
+}
+
+func Test_Provider_017(t *testing.T) {
+	// Tasks report their state and recent events
+	provider := New()
+	if _, err := provider.NewGraph(context.Background(), Config{Label_: "aa"}, FailConfig{Label_: "bb", Fail: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if task := provider.Get("task.aa"); task == nil || task.State != plugin.TaskCreated {
+		t.Error("Unexpected task", task)
+	}
+	if task := provider.Get("task.cc"); task != nil {
+		t.Error("Unexpected task", task)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- provider.Run(ctx)
+	}()
+
+	// Check the state of the tasks whilst running
+	time.Sleep(50 * time.Millisecond)
+	tasks := provider.Tasks()
+	if len(tasks) != 2 || tasks[0].Key != "task.aa" || tasks[1].Key != "fail.bb" {
+		t.Fatal("Unexpected tasks", tasks)
+	}
+	if tasks[0].State != plugin.TaskRunning || len(tasks[0].Events) != 1 || tasks[0].Events[0].Key() != "start" {
+		t.Error("Unexpected task", tasks[0])
+	}
+	if tasks[1].State != plugin.TaskFailed || !errors.Is(tasks[1].Err, ErrUnexpectedResponse) {
+		t.Error("Unexpected task", tasks[1])
+	}
+
+	// Check the state of the tasks once stopped
+	cancel()
+	if err := <-result; !errors.Is(err, ErrUnexpectedResponse) {
+		t.Error("Expected ErrUnexpectedResponse, got:", err)
+	}
+	if task := provider.Get("task.aa"); task == nil || task.State != plugin.TaskStopped {
+		t.Error("Unexpected task", task)
+	}
+}
+
+func Test_Provider_018(t *testing.T) {
+	// Events from tasks are received keyed by the task
+	provider := New()
+	if _, err := provider.New(context.Background(), Config{Label_: "aa"}); err != nil {
+		t.Fatal(err)
+	}
+	ch := provider.SubTasks()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- provider.Run(ctx)
+	}()
+
+	// Receive the start event from the task
+	evt := <-ch
+	if evt.Key() != "task.aa" || evt.Value().(iface.Event).Key() != "start" {
+		t.Error("Unexpected event", evt)
+	}
+
+	// The channel is closed when the provider stops
+	cancel()
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	for evt := range ch {
+		if evt.Key() != "task.aa" {
+			t.Error("Unexpected event", evt)
+		}
+	}
+
+	// Unsubscribing a closed channel is not an error
+	provider.UnsubTasks(ch)
+}
+
+func Test_Provider_019(t *testing.T) {
+	// Configurations for a router, an access log and a gateway
+	path := filepath.Join(t.TempDir(), "log")
+	configs := func(prefix, format string) []iface.TaskPlugin {
+		return []iface.TaskPlugin{
+			router.Config{},
+			logger.Config{Path: path},
+			accesslog.Config{Format: format, Router: types.Task{Ref: "router.router"}, Logger: types.Task{Ref: "logger.logger"}},
+			metrics.Config{Prefix: prefix, Middleware: []string{"accesslog"}, Router: types.Task{Ref: "router.router"}},
+		}
+	}
+	provider := New()
+	tasks, err := provider.NewGraph(context.Background(), configs("", "")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := tasks[0].(http.Handler)
+
+	// Run the provider, and wait for it to stop before the folder is removed
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() { cancel(); <-done }()
+	go func() { defer close(done); provider.Run(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// Make a request, and return the status code and the last access log entry
+	get := func(path_ string) (int, string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path_, nil))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		return w.Code, lines[len(lines)-1]
+	}
+	if code, _ := get("/metrics"); code != http.StatusOK {
+		t.Error("Unexpected status code", code)
+	}
+
+	// Replacing the gateway removes the routes for the previous prefix
+	if changes, err := provider.Reload(context.Background(), configs("/v2", "")...); err != nil {
+		t.Fatal(err)
+	} else if len(changes.Replaced) != 1 || changes.Replaced[0] != "metrics.metrics" {
+		t.Error("Unexpected changes", changes)
+	}
+	if code, _ := get("/metrics"); code != http.StatusNotFound {
+		t.Error("Unexpected status code", code)
+	}
+	if code, entry := get("/v2/metrics"); code != http.StatusOK {
+		t.Error("Unexpected status code", code)
+	} else if !strings.Contains(entry, "GET /v2/metrics") {
+		t.Error("Unexpected entry", entry)
+	}
+
+	// Replacing the access log replaces the middleware
+	if changes, err := provider.Reload(context.Background(), configs("/v2", accesslog.FormatJSON)...); err != nil {
+		t.Fatal(err)
+	} else if len(changes.Replaced) != 1 || changes.Replaced[0] != "accesslog.accesslog" {
+		t.Error("Unexpected changes", changes)
+	}
+	if code, entry := get("/v2/metrics"); code != http.StatusOK {
+		t.Error("Unexpected status code", code)
+	} else if !strings.Contains(entry, "method") {
+		t.Error("Unexpected entry", entry)
+	}
+
+	// A configuration which cannot be created is rolled back
+	if _, err := provider.Reload(context.Background(), configs("/v2", "xml")...); !errors.Is(err, ErrBadParameter) {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if provider.Get("accesslog.accesslog") == nil {
+		t.Error("Expected access log to be created again")
+	}
+	if code, entry := get("/v2/metrics"); code != http.StatusOK {
+		t.Error("Unexpected status code", code)
+	} else if !strings.Contains(entry, "method") {
+		t.Error("Unexpected entry", entry)
+	}
+}
+
+func Test_Provider_020(t *testing.T) {
+	// A policy which cannot be parsed is an error
+	if _, err := New().New(context.Background(), FailConfig{Label_: "test", Policy: &types.Policy{Restart: "sometimes"}}); !errors.Is(err, ErrBadParameter) {
+		t.Error("Expected ErrBadParameter, got:", err)
+	}
+
+	// The policy in the configuration overrides the default policy
+	provider := New()
+	provider.Policy = Policy{MaxRestarts: 1}
+	if _, err := provider.New(context.Background(), FailConfig{Label_: "test", Fail: 2, Policy: &types.Policy{Restart: "on-failure", Backoff: types.Duration(time.Millisecond)}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := provider.Run(ctx); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Error("Expected ErrUnexpectedResponse, got:", err)
+	}
+
+	// The error before the restart is recorded with the task
+	task := provider.Get("fail.test")
+	if task == nil {
+		t.Fatal("Expected task")
+	}
+	var errs int
+	for _, evt := range task.Events {
+		if evt.Error() != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Error("Unexpected events", task.Events)
+	}
+}
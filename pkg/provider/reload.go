@@ -0,0 +1,253 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	// Module imports
+	multierror "github.com/hashicorp/go-multierror"
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Changes describes the tasks which were added, removed and replaced
+// when configurations were reloaded
+type Changes struct {
+	Added    []string
+	Removed  []string
+	Replaced []string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (c Changes) String() string {
+	str := "<changes"
+	if len(c.Added) > 0 {
+		str += fmt.Sprintf(" added=%q", c.Added)
+	}
+	if len(c.Removed) > 0 {
+		str += fmt.Sprintf(" removed=%q", c.Removed)
+	}
+	if len(c.Replaced) > 0 {
+		str += fmt.Sprintf(" replaced=%q", c.Replaced)
+	}
+	return str + ">"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Reload compares configurations with the configurations used to create
+// the existing tasks, and removes, creates or replaces the tasks where the
+// configuration changed. Tasks which depend on a removed or replaced task are
+// also replaced. Other tasks are not affected. When a task cannot be created,
+// the changes are rolled back and the error is returned. A ProviderReload
+// event is emitted when any tasks were changed
+func (p *provider) Reload(ctx context.Context, configs ...iface.TaskPlugin) (Changes, error) {
+	var changes Changes
+
+	// Index configurations by name.label
+	next := make(map[string]iface.TaskPlugin, len(configs))
+	for _, config := range configs {
+		key := keyForConfig(config)
+		if _, exists := next[key]; exists {
+			return changes, ErrDuplicateEntry.Withf("Resource %q defined more than once", key)
+		}
+		next[key] = config
+	}
+
+	// Determine the tasks to stop, where the configuration was removed or
+	// changed, or where the task depends on a task which is stopped. Tasks
+	// are in creation order, so dependencies are visited first
+	p.mu.Lock()
+	stop := make(map[string]bool)
+	for _, key := range p.order {
+		if config, exists := p.configs[key]; exists {
+			if config_, exists := next[key]; !exists || !equalConfig(config, config_) {
+				p.stopping(key, stop)
+			}
+		}
+		for _, dep := range p.deps[key] {
+			if stop[dep] {
+				p.stopping(key, stop)
+			}
+		}
+	}
+
+	// Determine the configurations to create
+	var create []iface.TaskPlugin
+	for _, config := range configs {
+		key := keyForConfig(config)
+		if _, exists := p.configs[key]; !exists {
+			changes.Added = append(changes.Added, key)
+			create = append(create, config)
+		} else if stop[key] {
+			changes.Replaced = append(changes.Replaced, key)
+			create = append(create, config)
+		}
+	}
+
+	// Determine the tasks to remove, dependents first, and their configurations
+	// which are used to create the tasks again on rollback
+	var remove []string
+	var previous []iface.TaskPlugin
+	for i := len(p.order) - 1; i >= 0; i-- {
+		key := p.order[i]
+		if _, exists := p.configs[key]; !exists || !stop[key] {
+			continue
+		}
+		remove = append(remove, key)
+		previous = append(previous, unresolve(p.configs[key]))
+		if _, exists := next[key]; !exists {
+			changes.Removed = append([]string{key}, changes.Removed...)
+		}
+	}
+
+	// Check the configurations can be created once the tasks are removed
+	create, err := order(create, func(key string) bool {
+		_, exists := p.tasks[key]
+		return exists && !stop[key]
+	})
+	p.mu.Unlock()
+	if err != nil {
+		return changes, err
+	}
+
+	// Return if nothing changed
+	if len(create) == 0 && len(remove) == 0 {
+		return changes, nil
+	}
+
+	// Remove tasks, then create tasks in dependency order, rolling back
+	// on error
+	for i, key := range remove {
+		if err := p.Remove(key); err != nil {
+			return Changes{}, p.rollback(ctx, nil, previous[:i], err)
+		}
+	}
+	var created []string
+	for _, config := range create {
+		if _, err := p.New(ctx, config); err != nil {
+			return Changes{}, p.rollback(ctx, created, previous, fmt.Errorf("%v: %w", keyForConfig(config), err))
+		}
+		created = append(created, keyForConfig(config))
+	}
+
+	// Emit an event describing the changes
+	p.Emit(event.NewEvent(plugin.ProviderReload, changes))
+
+	// Return success
+	return changes, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// rollback removes the tasks which were created, dependents first, and then
+// creates the removed tasks again from their previous configurations. Returns
+// the error which caused the rollback, and any errors from the rollback
+func (p *provider) rollback(ctx context.Context, created []string, previous []iface.TaskPlugin, err error) error {
+	result := err
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := p.Remove(created[i]); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	previous, err = order(previous, func(key string) bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		_, exists := p.tasks[key]
+		return exists
+	})
+	if err != nil {
+		return multierror.Append(result, err)
+	}
+	for _, config := range previous {
+		if _, err := p.New(ctx, config); err != nil {
+			result = multierror.Append(result, fmt.Errorf("%v: %w", keyForConfig(config), err))
+		}
+	}
+	return result
+}
+
+// stopping marks a task and the tasks created whilst creating it as stopped.
+// The provider lock is held by the caller
+func (p *provider) stopping(key string, stop map[string]bool) {
+	stop[key] = true
+	for _, child := range p.children[key] {
+		p.stopping(child, stop)
+	}
+}
+
+// equalConfig returns true if two configurations are equal, where references
+// to other tasks are compared by key
+func equalConfig(a, b iface.TaskPlugin) bool {
+	return equalValue(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func equalValue(a, b reflect.Value) bool {
+	if a.IsValid() != b.IsValid() {
+		return false
+	} else if !a.IsValid() {
+		return true
+	} else if a.Type() != b.Type() {
+		return false
+	}
+
+	// Compare task references by key
+	if a.Type() == typeTask {
+		if ref := a.FieldByName("Ref").String(); ref != "" || b.FieldByName("Ref").String() != "" {
+			return ref == b.FieldByName("Ref").String()
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValue(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValue(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValue(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			if v := b.MapIndex(k); !v.IsValid() || !equalValue(a.MapIndex(k), v) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		return a.IsNil() && b.IsNil()
+	case reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	default:
+		return a.Equal(b)
+	}
+}
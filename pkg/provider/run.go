@@ -0,0 +1,260 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	// Module imports
+	multierror "github.com/hashicorp/go-multierror"
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// runner is the state of a running task
+type runner struct {
+	cancel  context.CancelFunc
+	remove  chan struct{} // Closed when the task is removed
+	exited  chan struct{} // Closed when the task has exited
+	stopped chan struct{} // Closed when the task has exited or been abandoned
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Run all tasks until the context is cancelled, or all tasks have exited.
+// Tasks created whilst running are started immediately. When the context is
+// cancelled, each task is cancelled once the tasks which depend on it have
+// exited, and each task has a grace period in which to exit
+func (p *provider) Run(ctx context.Context) error {
+	p.mu.Lock()
+	if p.running != nil {
+		p.mu.Unlock()
+		return ErrOutOfOrder.With("Provider is already running")
+	}
+	p.ctx = ctx
+	p.running = make(map[string]*runner, len(p.tasks))
+	p.result = nil
+
+	// Start all tasks
+	for _, key := range p.order {
+		p.start(key)
+	}
+
+	// Wait until all tasks are stopped
+	for len(p.running) > 0 {
+		p.active.Wait()
+	}
+	p.running = nil
+	result := p.result
+	p.mu.Unlock()
+
+	// Close channel
+	p.Emit(nil)
+
+	// Return any errors
+	return result
+}
+
+// Remove stops a task and removes it from the provider, together with any
+// tasks created whilst creating it which are not referenced by other tasks.
+// It is an error to remove a task which is referenced by another task. Tasks
+// which implement io.Closer are closed once they have been removed
+func (p *provider) Remove(key string) error {
+	p.mu.Lock()
+	if _, exists := p.tasks[key]; !exists {
+		p.mu.Unlock()
+		return ErrNotFound.Withf("Resource %q not found", key)
+	}
+
+	// Determine the tasks to remove, dependents first
+	keys := p.removable(key, nil)
+	for _, key_ := range keys {
+		for _, dependent := range p.dependents(key_) {
+			if !contains(keys, dependent) {
+				p.mu.Unlock()
+				return ErrBadParameter.Withf("Resource %q is referenced by %q", key_, dependent)
+			}
+		}
+	}
+
+	// Stop the tasks
+	tasks := make([]iface.Task, 0, len(keys))
+	for _, key := range keys {
+		r := p.running[key]
+		if r != nil {
+			close(r.remove)
+			p.mu.Unlock()
+			<-r.stopped
+			p.mu.Lock()
+		}
+		tasks = append(tasks, p.tasks[key])
+		p.delete(key)
+	}
+	p.mu.Unlock()
+
+	// Close the tasks which release resources held in other tasks, such as
+	// handlers added to a router, and emit events for removed tasks
+	for i, key := range keys {
+		if closer, ok := tasks[i].(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				p.Emit(event.NewError(fmt.Errorf("%v: %w", key, err)))
+			}
+		}
+		p.Emit(event.NewEvent(plugin.ProviderTaskRemoved, key))
+	}
+
+	// Return success
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// start runs a task. The provider lock is held by the caller
+func (p *provider) start(key string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &runner{
+		cancel:  cancel,
+		remove:  make(chan struct{}),
+		exited:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	p.running[key] = r
+	p.status[key].state = plugin.TaskRunning
+	task, policy := p.tasks[key], p.policyFor(key)
+
+	// Run task, restarting it according to the policy for the task
+	go func() {
+		defer close(r.exited)
+		if err := p.supervise(ctx, key, task, policy); err != nil {
+			p.setState(key, plugin.TaskFailed, err)
+			p.fail(fmt.Errorf("%v: %w", key, err))
+		} else {
+			p.setState(key, plugin.TaskStopped, nil)
+		}
+		p.Emit(event.NewEvent(plugin.ProviderTaskExit, key))
+	}()
+
+	// Stop task when the context is cancelled, after the tasks which depend
+	// on it have stopped, or when the task is removed
+	go func(parent context.Context) {
+		defer func() {
+			cancel()
+			close(r.stopped)
+			p.mu.Lock()
+			delete(p.running, key)
+			p.active.Broadcast()
+			p.mu.Unlock()
+		}()
+		select {
+		case <-r.exited:
+			return
+		case <-r.remove:
+		case <-parent.Done():
+			p.mu.Lock()
+			var stopped []chan struct{}
+			for _, dependent := range p.dependents(key) {
+				if r := p.running[dependent]; r != nil {
+					stopped = append(stopped, r.stopped)
+				}
+			}
+			p.mu.Unlock()
+			for _, ch := range stopped {
+				<-ch
+			}
+		}
+		cancel()
+		timer := time.NewTimer(p.Grace)
+		defer timer.Stop()
+		select {
+		case <-r.exited:
+		case <-timer.C:
+			p.setState(key, plugin.TaskFailed, ErrInternalAppError.Withf("did not exit within %v", p.Grace))
+			p.fail(ErrInternalAppError.Withf("%v: did not exit within %v", key, p.Grace))
+			p.Emit(event.NewEvent(plugin.ProviderTaskTimeout, key))
+		}
+	}(p.ctx)
+}
+
+// fail records an error from a task
+func (p *provider) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = multierror.Append(p.result, err)
+}
+
+// policyFor returns the supervision policy for a task. The provider lock
+// is held by the caller
+func (p *provider) policyFor(key string) Policy {
+	if policy, exists := p.policies[key]; exists {
+		return policy
+	} else {
+		return p.Policy
+	}
+}
+
+// dependents returns the tasks which depend on a task. The provider lock
+// is held by the caller
+func (p *provider) dependents(key string) []string {
+	var result []string
+	for _, key_ := range p.order {
+		if contains(p.deps[key_], key) {
+			result = append(result, key_)
+		}
+	}
+	return result
+}
+
+// removable returns the task and the tasks created whilst creating it which
+// are not referenced by other tasks, dependents first. The provider lock is
+// held by the caller
+func (p *provider) removable(key string, result []string) []string {
+	result = append(result, key)
+	for _, child := range p.children[key] {
+		referenced := false
+		for _, dependent := range p.dependents(child) {
+			if dependent != key {
+				referenced = true
+			}
+		}
+		if !referenced {
+			result = p.removable(child, result)
+		}
+	}
+	return result
+}
+
+// delete removes a task. The provider lock is held by the caller
+func (p *provider) delete(key string) {
+	delete(p.tasks, key)
+	delete(p.deps, key)
+	delete(p.children, key)
+	delete(p.policies, key)
+	delete(p.configs, key)
+	delete(p.status, key)
+	for i, key_ := range p.order {
+		if key_ == key {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// contains returns true if a slice contains a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
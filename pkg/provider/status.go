@@ -0,0 +1,114 @@
+package provider
+
+import (
+	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// status is the state of a task and a ring buffer of recent events
+// emitted by the task
+type status struct {
+	state  plugin.TaskState
+	err    error
+	events []iface.Event
+	next   int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Tasks returns the tasks in the order they were created, with their state
+// and recent events
+func (p *provider) Tasks() []plugin.ProviderTask {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make([]plugin.ProviderTask, 0, len(p.order))
+	for _, key := range p.order {
+		result = append(result, p.taskForKey(key))
+	}
+	return result
+}
+
+// Get returns a task by key with its state and recent events, or nil if
+// the task does not exist
+func (p *provider) Get(key string) *plugin.ProviderTask {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.tasks[key]; !exists {
+		return nil
+	}
+	task := p.taskForKey(key)
+	return &task
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// taskForKey returns the description of a task. The provider lock is held
+// by the caller
+func (p *provider) taskForKey(key string) plugin.ProviderTask {
+	task := plugin.ProviderTask{Key: key, Task: p.tasks[key]}
+	if s := p.status[key]; s != nil {
+		task.State = s.state
+		task.Err = s.err
+		task.Events = s.recent()
+	}
+	return task
+}
+
+// setState sets the state of a task, and the error when the task failed
+func (p *provider) setState(key string, state plugin.TaskState, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s := p.status[key]; s != nil {
+		s.state = state
+		if err != nil {
+			s.err = err
+		}
+	}
+}
+
+// record adds an event emitted by a task to the recent events for the task,
+// redacting the value of a sensitive event
+func (p *provider) record(key string, evt iface.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s := p.status[key]; s != nil && p.Events > 0 {
+		s.add(redact(evt), int(p.Events))
+	}
+}
+
+// redact returns an event without the value or error when the key of the
+// event is sensitive, or returns the event otherwise
+func redact(evt iface.Event) iface.Event {
+	if key, ok := evt.Key().(plugin.Sensitive); ok && key.Sensitive() {
+		return event.NewEvent(evt.Key(), nil)
+	}
+	return evt
+}
+
+// add an event to the ring buffer, overwriting the oldest event when the
+// buffer is full
+func (s *status) add(evt iface.Event, size int) {
+	if len(s.events) < size {
+		s.events = append(s.events, evt)
+	} else {
+		s.events[s.next%len(s.events)] = evt
+	}
+	s.next++
+}
+
+// recent returns the events in the ring buffer, oldest first
+func (s *status) recent() []iface.Event {
+	n := len(s.events)
+	result := make([]iface.Event, 0, n)
+	for i := 0; i < n; i++ {
+		result = append(result, s.events[(s.next+i)%n])
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// observer is a function which is called for every event from tasks
+type observer struct {
+	fn func(string, iface.Event)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// SubTasks returns a buffered channel on which events from all tasks are
+// received, where the key of each event is the task key and the value is the
+// event. Provider events for a task are keyed by the task, and other provider
+// events are keyed by the provider label. Events are dropped when the channel
+// is full, and the channel is closed when the provider stops running
+func (p *provider) SubTasks() <-chan iface.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch := make(chan iface.Event, p.StreamCap)
+	p.streams = append(p.streams, ch)
+	return ch
+}
+
+// UnsubTasks closes a channel returned by SubTasks. It is not an error to
+// unsubscribe a channel which has already been closed
+func (p *provider) UnsubTasks(ch <-chan iface.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, ch_ := range p.streams {
+		if ch_ == ch {
+			close(ch_)
+			p.streams = append(p.streams[:i], p.streams[i+1:]...)
+			return
+		}
+	}
+}
+
+// ObserveTasks calls a function for every event from tasks, with the key and
+// the event as they are sent to the channels returned by SubTasks. Unlike
+// SubTasks, no events are dropped, as the function is called by the task
+// which emitted the event, so the function should return quickly. The
+// function is called until the returned function is called
+func (p *provider) ObserveTasks(fn func(string, iface.Event)) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	o := &observer{fn}
+	p.observers = append(p.observers, o)
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, o_ := range p.observers {
+			if o_ == o {
+				p.observers = append(p.observers[:i:i], p.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Emit sends a provider event to subscribers and to the channels returned by
+// SubTasks. Emitting nil closes all channels
+func (p *provider) Emit(evt iface.Event) bool {
+	if evt == nil {
+		p.mu.Lock()
+		for _, ch := range p.streams {
+			close(ch)
+		}
+		p.streams = nil
+		p.mu.Unlock()
+	} else {
+		p.stream(p.keyForEvent(evt), evt)
+	}
+	return p.PubSub.Emit(evt)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// stream sends an event to the channels returned by SubTasks without
+// blocking, and then calls the observers. The value of a sensitive event
+// is redacted
+func (p *provider) stream(key string, evt iface.Event) {
+	p.mu.Lock()
+	if len(p.streams) == 0 && len(p.observers) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	evt = redact(evt)
+	if len(p.streams) > 0 {
+		evt := event.NewEvent(key, evt)
+		for _, ch := range p.streams {
+			evt.Emit(ch)
+		}
+	}
+	observers := p.observers
+	p.mu.Unlock()
+
+	// Call the observers without the lock, so they can call the provider
+	for _, o := range observers {
+		o.fn(key, evt)
+	}
+}
+
+// keyForEvent returns the task key for a provider event, or the provider
+// label if the event does not refer to a task
+func (p *provider) keyForEvent(evt iface.Event) string {
+	if typ, ok := evt.Key().(plugin.ProviderEventType); ok && typ != plugin.ProviderReload {
+		if key, ok := evt.Value().(string); ok {
+			return key
+		}
+	}
+	return p.Label()
+}
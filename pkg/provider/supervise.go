@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// Module imports
+	iface "github.com/mutablelogic/terraform-provider-nginx"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// supervise runs a task until the context is cancelled, restarting it
+// according to the policy for the task, and returns the error from the
+// last run of the task
+func (p *provider) supervise(ctx context.Context, key string, task iface.Task, policy Policy) error {
+	var restarts uint
+	for {
+		// Subscribe before running the task so that no events are missed
+		ch := task.Sub()
+		done := make(chan struct{})
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			p.forward(key, task, ch, done)
+		}()
+
+		// Run the task
+		err := task.Run(ctx)
+		close(done)
+		<-forwarded
+
+		// Determine if the task failed
+		failed := err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+		if !failed {
+			err = nil
+		}
+
+		// Return if the provider is stopping, or the task should not be restarted
+		if ctx.Err() != nil || !policy.restart(restarts, failed) {
+			return err
+		} else if failed {
+			p.emit(key, event.NewError(err))
+		}
+
+		// Restart the task after a delay
+		timer := time.NewTimer(policy.backoff(restarts))
+		restarts++
+		p.setState(key, plugin.TaskRestarting, err)
+		p.Emit(event.NewEvent(plugin.ProviderTaskRestart, key))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+			p.setState(key, plugin.TaskRunning, nil)
+		}
+	}
+}
+
+// forward records and emits events from a task until the channel is closed
+// or done is closed, after which any buffered events are emitted and the
+// channel is unsubscribed
+func (p *provider) forward(key string, task iface.Task, ch <-chan iface.Event, done <-chan struct{}) {
+	if ch == nil {
+		return
+	}
+	for {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				return
+			}
+			p.emit(key, evt)
+		case <-done:
+			for {
+				select {
+				case evt, ok := <-ch:
+					if !ok {
+						return
+					}
+					p.emit(key, evt)
+				default:
+					task.Unsub(ch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// emit an event from a task, and panic if the event could not be emitted
+func (p *provider) emit(key string, evt iface.Event) {
+	if evt == nil {
+		return
+	}
+	p.record(key, evt)
+	p.stream(key, evt)
+	if !p.PubSub.Emit(evt) {
+		panic(fmt.Sprintln("Unable to emit: ", evt))
+	}
+}
@@ -9,6 +9,7 @@ import (
 	// Modules
 	iface "github.com/mutablelogic/terraform-provider-nginx"
 	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 )
 
@@ -18,7 +19,8 @@ import (
 // Config is the basic configuration for a task, which includes a simple
 // label for the task
 type Config struct {
-	Label_ string `hcl:"label,label" json:"label,omitempty"`
+	Label_ string        `hcl:"label,label" json:"label,omitempty"`
+	Policy *types.Policy `hcl:"policy,block" json:"policy,omitempty"` // Supervision policy for the task
 }
 
 // This is the most basic task, which just emits a single event on startup, and
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"container/list"
+	"sync"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// cache is a least-recently-used cache of routes, keyed by method and
+// path, which holds at most size entries
+type cache struct {
+	sync.Mutex
+
+	size    int
+	list    *list.List
+	entries map[string]*list.Element
+}
+
+// cached is a route and the parameters matched for a method and path
+type cached struct {
+	key     string
+	route   *route
+	matched []string
+}
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newCache returns a cache which holds at most size entries
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		list:    list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// get returns a route and matched parameters for a key, or nil if the
+// key is not in the cache
+func (c *cache) get(key string) (*route, []string) {
+	c.Lock()
+	defer c.Unlock()
+	if elem, exists := c.entries[key]; exists {
+		c.list.MoveToFront(elem)
+		entry := elem.Value.(*cached)
+		return entry.route, entry.matched
+	}
+	return nil, nil
+}
+
+// put adds a route and matched parameters for a key, evicting the least
+// recently used entry when the cache is full. Nothing is cached when the
+// size is zero
+func (c *cache) put(key string, route *route, matched []string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if elem, exists := c.entries[key]; exists {
+		c.list.MoveToFront(elem)
+		elem.Value = &cached{key, route, matched}
+		return
+	}
+	if c.list.Len() >= c.size {
+		if elem := c.list.Back(); elem != nil {
+			c.list.Remove(elem)
+			delete(c.entries, elem.Value.(*cached).key)
+		}
+	}
+	c.entries[key] = c.list.PushFront(&cached{key, route, matched})
+}
+
+// clear removes all entries from the cache
+func (c *cache) clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.list.Init()
+	c.entries = make(map[string]*list.Element, c.size)
+}
+
+// len returns the number of entries in the cache
+func (c *cache) len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.list.Len()
+}
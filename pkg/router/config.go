@@ -6,6 +6,7 @@ import (
 	// Namespace imports
 	. "github.com/djthorpe/go-errors"
 	. "github.com/mutablelogic/terraform-provider-nginx"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	"github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 )
 
@@ -13,15 +14,20 @@ import (
 // TYPES
 
 type Config struct {
-	L string `hcl:"label,label" json:"label,omitempty"`
+	L         string        `hcl:"label,label" json:"label,omitempty"`
+	CacheSize int           `hcl:"cache_size,optional" json:"cache_size,omitempty"` // Maximum number of cached routes
+	Policy    *types.Policy `hcl:"policy,block" json:"policy,omitempty"`            // Supervision policy for the task
 }
 
 /////////////////////////////////////////////////////////////////////
 // GLOBALS
 
 const (
-	DefaultLabel  = "router"
-	pathSeparator = "/"
+	DefaultLabel     = "router"
+	DefaultCacheSize = 1024
+	pathSeparator    = "/"
+
+	defaultEventChannelCapacity = 1000
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -44,9 +50,15 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 	if c.L == "" {
 		c.L = DefaultLabel
 	}
+	if c.CacheSize == 0 {
+		c.CacheSize = DefaultCacheSize
+	}
 	if !util.IsIdentifier(c.Label()) {
 		return nil, ErrBadParameter.Withf("label: %q", c.L)
 	}
+	if c.CacheSize < 0 {
+		return nil, ErrBadParameter.Withf("cache_size: %v", c.CacheSize)
+	}
 
 	// Return configuration
 	return NewWithConfig(c)
@@ -1,2 +1,7 @@
-// router package provides a way to route HTTP paths to HTTP handlers
+// router package provides a way to route HTTP paths to HTTP handlers.
+//
+// Routes are matched in order of prefix length, longest first. Matched
+// routes are kept in a least-recently-used cache keyed by method and path,
+// bounded by the cache_size parameter, and routes can be added safely
+// whilst requests are being served.
 package router
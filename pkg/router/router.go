@@ -7,9 +7,12 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	// Module imports
+	multierror "github.com/hashicorp/go-multierror"
 	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 	slices "golang.org/x/exp/slices"
@@ -26,29 +29,42 @@ type router struct {
 	provider.Task
 	sync.RWMutex
 
-	routes []route
-	cache  map[string]*cached
+	routes  []*route
+	cache   *cache
+	running bool // Set once the router is running, when routes must resolve
 	middleware
 }
 
-type cached struct {
-	index   int
-	matched []string
+// headWriter discards the body of a response to a HEAD request
+type headWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
 }
 
 type route struct {
+	gateway Gateway // The gateway which added the route
 	prefix  string
 	path    *regexp.Regexp
 	fn      http.HandlerFunc
 	methods []string
+	chain   []string         // Middleware declared by the gateway
+	handler http.HandlerFunc // Handler wrapped with middleware, or nil if not yet resolved
 }
 
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	allowKey = "Allow"
+)
+
 /////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
 func NewWithConfig(c Config) (Router, error) {
 	r := new(router)
-	r.cache = make(map[string]*cached)
+	r.Cap = defaultEventChannelCapacity
+	r.cache = newCache(c.CacheSize)
 
 	// Return success
 	return r, nil
@@ -58,7 +74,10 @@ func NewWithConfig(c Config) (Router, error) {
 // STRINGIFY
 
 func (r *router) String() string {
+	r.RLock()
+	defer r.RUnlock()
 	str := "<router"
+	str += fmt.Sprintf(" cache=%v/%v", r.cache.len(), r.cache.size)
 	for _, route := range r.routes {
 		str += fmt.Sprintf(" %q %q => %q", route.prefix, route.path, route.methods)
 	}
@@ -71,122 +90,282 @@ func (r *router) String() string {
 // AddHandler adds a handler to the router, for a specific prefix and http methods supported.
 // If the path argument is nil, then any path under the prefix will match. If the path contains
 // a regular expression, then a match is made and any matched parameters of the regular
-// expression can be retrieved from the request context.
+// expression can be retrieved from the request context. Once the router is running,
+// an error is returned if the gateway declares middleware which has not been added.
 func (r *router) AddHandler(gateway Gateway, path *regexp.Regexp, fn http.HandlerFunc, methods ...string) error {
 	// Check gateway
 	if gateway == nil {
 		return ErrBadParameter.With("gateway")
 	}
 
+	if fn == nil {
+		return ErrBadParameter.With("fn")
+	}
+
 	// If methods is empty, default to GET
 	if len(methods) == 0 {
 		methods = []string{"GET"}
 	}
 
-	// Append the route
-	r.routes = append(r.routes, route{normalizePath(gateway.Prefix(), true), path, fn, methods})
+	r.Lock()
+	defer r.Unlock()
 
-	// Sort routes by prefix length, longest first, and then by path != nil vs nil
-	sort.Slice(r.routes, func(i, j int) bool {
-		if len(r.routes[i].prefix) < len(r.routes[j].prefix) {
-			return false
-		}
-		if len(r.routes[i].prefix) == len(r.routes[j].prefix) && r.routes[i].path == nil {
-			return false
+	// Append the route, and wrap with middleware if it has already been added.
+	// Otherwise, the route is resolved when the middleware is added, unless the
+	// router is already running
+	route := &route{gateway: gateway, prefix: normalizePath(gateway.Prefix(), true), path: path, fn: fn, methods: methods, chain: gateway.Middleware()}
+	if handler, err := r.Wrap(fn, route.chain...); err == nil {
+		route.handler = handler
+	} else if r.running {
+		return err
+	}
+	r.routes = append(r.routes, route)
+
+	// Sort routes by prefix length, longest first, and then by path != nil vs nil,
+	// otherwise keeping the order in which routes were added
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		if len(r.routes[i].prefix) != len(r.routes[j].prefix) {
+			return len(r.routes[i].prefix) > len(r.routes[j].prefix)
 		}
-		return true
+		return r.routes[i].path != nil && r.routes[j].path == nil
 	})
 
+	// Invalidate the cache, as a new route may match cached paths
+	r.cache.clear()
+
 	// Return success
 	return nil
 }
 
-func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	route, params := r.get(req.Method, req.URL.Path)
-	if route == nil {
-		util.ServeError(w, http.StatusNotFound)
-		return
+// AddMiddleware adds a middleware handler with a unique name, and resolves any routes
+// which were waiting for it
+func (r *router) AddMiddleware(name string, fn func(http.HandlerFunc) http.HandlerFunc) error {
+	if !util.IsIdentifier(name) || fn == nil {
+		return ErrBadParameter.Withf("middleware: %q", name)
 	}
 
-	// Check methods
-	if slices.Contains(route.methods, req.Method) {
-		route.fn(w, req.Clone(context.WithPrefixParams(req.Context(), route.prefix, params)))
-		return
+	r.Lock()
+	defer r.Unlock()
+
+	// Add the middleware
+	if err := r.add(name, fn); err != nil {
+		return err
 	}
 
-	// Return method not allowed
-	util.ServeError(w, http.StatusMethodNotAllowed)
+	// Resolve routes, ignoring routes which are waiting for other middleware
+	r.resolve()
+
+	// Return success
+	return nil
 }
 
-/////////////////////////////////////////////////////////////////////
-// PRIVATE METHODS
+// RemoveHandler removes all the routes which were added by a gateway. Returns
+// ErrNotFound if the gateway did not add any routes
+func (r *router) RemoveHandler(gateway Gateway) error {
+	if gateway == nil {
+		return ErrBadParameter.With("gateway")
+	}
 
-// get returns the route for the given path and method, and the parameters matched
-// or returns nil for the route otherwise
-func (r *router) get(method, path string) (*route, []string) {
-	// Check cache
-	if route, params := r.getcached(method, path); route != nil {
-		return route, params
+	r.Lock()
+	defer r.Unlock()
+
+	// Remove the routes, keeping the order of other routes
+	routes := make([]*route, 0, len(r.routes))
+	for _, route := range r.routes {
+		if route.gateway != gateway {
+			routes = append(routes, route)
+		}
 	}
+	if len(routes) == len(r.routes) {
+		return ErrNotFound.Withf("gateway: %q", gateway.Prefix())
+	}
+	r.routes = routes
 
-	// Search routes to find candidates
-	methodAllowed := true
-	for i := range r.routes {
-		route := r.routes[i]
+	// Invalidate the cache, as cached paths may refer to removed routes
+	r.cache.clear()
 
-		// Check against the prefix
-		if !strings.HasPrefix(path, route.prefix) {
-			continue
+	// Return success
+	return nil
+}
+
+// RemoveMiddleware removes a middleware handler by name. Routes which use the
+// middleware are unresolved until middleware with the same name is added again
+func (r *router) RemoveMiddleware(name string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	// Remove the middleware
+	if err := r.remove(name); err != nil {
+		return err
+	}
+
+	// Unresolve routes which use the middleware
+	for _, route := range r.routes {
+		if contains(route.chain, name) {
+			route.handler = nil
 		}
+	}
 
-		// Check for default route: this is the route that matches everything
-		if route.path == nil {
-			if contains(route.methods, method) {
-				r.setcached(method, path, i, nil)
-				return &route, nil
-			}
-			methodAllowed = false
-			continue
+	// Return success
+	return nil
+}
+
+// ServeHTTP serves a request with the matching route, and emits a
+// RouterRequest event once the request has been served
+func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	rw := util.NewResponseWriter(w)
+	route := r.serve(rw, req)
+
+	// Emit the request
+	info := RequestInfo{Method: req.Method, Status: rw.Status, Duration: time.Since(start)}
+	if route != nil {
+		info.Prefix = route.prefix
+		if route.path != nil {
+			info.Path = route.path.String()
 		}
+	}
+	r.Emit(event.NewEvent(RouterRequest, info))
+}
 
-		// Check with a regular expression
-		relpath := normalizePath(path[len(route.prefix):], false)
-		if params := route.path.FindStringSubmatch(relpath); params != nil {
-			if contains(route.methods, method) {
-				r.setcached(method, path, i, params[1:])
-				return &route, nil
-			}
-			methodAllowed = false
-			continue
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// serve a request, and return the route which matched the request
+// or nil if no route matched. A HEAD request is served by the GET handler
+// when there is no HEAD handler, and an OPTIONS request is answered with
+// the allowed methods when there is no OPTIONS handler
+func (r *router) serve(w http.ResponseWriter, req *http.Request) *route {
+	route, params := r.get(req.Method, req.URL.Path)
+	if route == nil && req.Method == http.MethodHead {
+		if route, params = r.get(http.MethodGet, req.URL.Path); route != nil {
+			w = &headWriter{ResponseWriter: w}
 		}
 	}
 
-	if !methodAllowed {
-		fmt.Println("TODO: methodNotAllowed", method, path)
+	// Return not found, the allowed methods or method not allowed
+	if route == nil {
+		allowed := r.allowed(req.URL.Path)
+		if len(allowed) == 0 {
+			util.ServeError(w, http.StatusNotFound)
+		} else if req.Method == http.MethodOptions {
+			w.Header().Set(allowKey, strings.Join(allowed, ", "))
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.Header().Set(allowKey, strings.Join(allowed, ", "))
+			util.ServeError(w, http.StatusMethodNotAllowed)
+		}
+		return nil
+	}
+
+	// Serve the request
+	if route.handler == nil {
+		util.ServeError(w, http.StatusInternalServerError, "middleware not resolved for", route.prefix)
+	} else {
+		route.handler(w, req.Clone(context.WithPrefixParams(req.Context(), route.prefix, params)))
+	}
+	return route
+}
+
+// get returns a copy of the route for the given path and method, and the parameters
+// matched or returns nil for the route otherwise. Only paths which match a route are
+// cached, and the cache is bounded, so requests for random paths cannot exhaust memory
+func (r *router) get(method, path string) (*route, []string) {
+	r.RLock()
+	defer r.RUnlock()
+
+	// Check cache
+	key := method + path
+	if route, params := r.cache.get(key); route != nil {
+		return route.copy(), params
+	}
+
+	// Search routes to find a route which matches the path and method
+	for _, route := range r.routes {
+		if params, match := route.match(path); match && contains(route.methods, method) {
+			r.cache.put(key, route, params)
+			return route.copy(), params
+		}
 	}
 
 	// No match
 	return nil, nil
 }
 
-// getcached returns the route for the given path, and the parameters matched
-// or returns nil for the route otherwise
-func (r *router) getcached(method, path string) (*route, []string) {
+// allowed returns the methods allowed for a path in sorted order, or nil if
+// no route matches the path. HEAD is allowed when GET is allowed, and
+// OPTIONS is always allowed
+func (r *router) allowed(path string) []string {
 	r.RLock()
 	defer r.RUnlock()
-	cached, exists := r.cache[method+path]
-	if !exists {
-		return nil, nil
-	} else {
-		return &r.routes[cached.index], cached.matched
+	var result []string
+	for _, route := range r.routes {
+		if _, match := route.match(path); match {
+			for _, method := range route.methods {
+				if !contains(result, method) {
+					result = append(result, method)
+				}
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	if contains(result, http.MethodGet) && !contains(result, http.MethodHead) {
+		result = append(result, http.MethodHead)
+	}
+	if !contains(result, http.MethodOptions) {
+		result = append(result, http.MethodOptions)
 	}
+	sort.Strings(result)
+	return result
 }
 
-// setcached puts a route into the cache
-func (r *router) setcached(method, path string, index int, params []string) {
-	r.Lock()
-	defer r.Unlock()
-	r.cache[method+path] = &cached{index, params}
+// resolve wraps any unresolved routes with their middleware, and returns
+// an error for each route with middleware which has not been added
+func (r *router) resolve() error {
+	var result error
+	for _, route := range r.routes {
+		if route.handler != nil {
+			continue
+		}
+		if handler, err := r.Wrap(route.fn, route.chain...); err != nil {
+			result = multierror.Append(result, fmt.Errorf("%q: %w", route.prefix, err))
+		} else {
+			route.handler = handler
+		}
+	}
+	return result
+}
+
+// copy returns a copy of the route, which can be used whilst the
+// router is unlocked
+func (route *route) copy() *route {
+	result := *route
+	return &result
+}
+
+// match returns true and any matched parameters if the route matches
+// the path
+func (route *route) match(path string) ([]string, bool) {
+	// Check against the prefix
+	if !strings.HasPrefix(path, route.prefix) {
+		return nil, false
+	}
+
+	// Check for default route: this is the route that matches everything
+	if route.path == nil {
+		return nil, true
+	}
+
+	// Check with a regular expression
+	relpath := normalizePath(path[len(route.prefix):], false)
+	if params := route.path.FindStringSubmatch(relpath); params != nil {
+		return params[1:], true
+	}
+
+	// No match
+	return nil, false
 }
 
 // Add a / to the beginning and optionally to the end of the path
@@ -200,6 +379,20 @@ func normalizePath(path string, end bool) string {
 	return path
 }
 
+// WriteHeader writes the header
+func (w *headWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the header if it has not been written, and discards the data
+func (w *headWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return len(data), nil
+}
+
 // contains returns true if a string array contains a string
 func contains(a []string, s string) bool {
 	return slices.Contains(a, s)
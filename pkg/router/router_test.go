@@ -2,18 +2,24 @@ package router_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	// Module import
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
 	plugin "github.com/mutablelogic/terraform-provider-nginx/plugin"
 
 	// Namespace imports
-	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/djthorpe/go-errors"
 	. "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
 )
 
@@ -107,15 +113,380 @@ func Test_Router_002(t *testing.T) {
 	}
 }
 
+func Test_Router_003(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a route for '/A' before the middleware is added
+	if err := router.(plugin.Router).AddHandler(Gateway("/A", "aa", "bb"), nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("/A"))
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// Add middleware, which should be called from left to right
+	for _, name := range []string{"aa", "bb"} {
+		name := name
+		if err := router.(plugin.Router).AddMiddleware(name, func(fn http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(name))
+				fn(w, r)
+			}
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Adding middleware twice should fail
+	if err := router.(plugin.Router).AddMiddleware("aa", func(fn http.HandlerFunc) http.HandlerFunc { return fn }); !errors.Is(err, ErrDuplicateEntry) {
+		t.Error("Expected ErrDuplicateEntry, got", err)
+	}
+
+	// Add a route for '/B' after the middleware is added
+	if err := router.(plugin.Router).AddHandler(Gateway("/B", "bb"), nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("/B"))
+	}); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		Path     string
+		Expected string
+	}{
+		{"/A/", "aabb/A"},
+		{"/B/", "bb/B"},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.Path, nil))
+		if body, _ := io.ReadAll(w.Result().Body); string(body) != test.Expected {
+			t.Errorf("Test %d: unexpected body: %q", i, body)
+		}
+	}
+}
+
+func Test_Router_004(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a route with middleware which is never added
+	if err := router.(plugin.Router).AddHandler(Gateway("/A", "unknown"), nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("/A"))
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// Running the router should fail immediately
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := router.Run(ctx); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func Test_Router_005(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add GET and DELETE handlers for '/A/test', and a PUT handler for '/A'
+	// which matches any path
+	re := regexp.MustCompile("^/test$")
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		method := method
+		if err := router.(plugin.Router).AddHandler(Gateway("/A"), re, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Method", r.Method)
+			w.Write([]byte(method))
+		}, method); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := router.(plugin.Router).AddHandler(Gateway("/A"), nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(http.MethodPut))
+	}, http.MethodPut); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		Method, Path string
+		Code         int
+		Allow        string
+		Expected     string
+	}{
+		{http.MethodGet, "/A/test", http.StatusOK, "", http.MethodGet},
+		{http.MethodDelete, "/A/test", http.StatusOK, "", http.MethodDelete},
+		{http.MethodPut, "/A/test", http.StatusOK, "", http.MethodPut},
+		{http.MethodPost, "/A/test", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS, PUT", ""},
+		{http.MethodPost, "/A/other", http.StatusMethodNotAllowed, "OPTIONS, PUT", ""},
+		{http.MethodHead, "/A/test", http.StatusOK, "", ""},
+		{http.MethodHead, "/A/other", http.StatusMethodNotAllowed, "OPTIONS, PUT", ""},
+		{http.MethodOptions, "/A/test", http.StatusNoContent, "DELETE, GET, HEAD, OPTIONS, PUT", ""},
+		{http.MethodGet, "/B", http.StatusNotFound, "", ""},
+		{http.MethodOptions, "/B", http.StatusNotFound, "", ""},
+	}
+
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(test.Method, test.Path, nil))
+		body, _ := io.ReadAll(w.Result().Body)
+		if status := w.Result().StatusCode; status != test.Code {
+			t.Error("Test", i, ": unexpected status code: ", status)
+		} else if allow := w.Result().Header.Get("Allow"); allow != test.Allow {
+			t.Errorf("Test %d: unexpected Allow header: %q", i, allow)
+		} else if test.Expected != "" && string(body) != test.Expected {
+			t.Errorf("Test %d: unexpected body: %q", i, body)
+		}
+	}
+
+	// HEAD is served by the GET handler without a body
+	w := httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/A/test", nil))
+	if method := w.Result().Header.Get("X-Method"); method != http.MethodHead {
+		t.Error("Unexpected method", method)
+	} else if w.Body.Len() != 0 {
+		t.Error("Unexpected body", w.Body.String())
+	}
+}
+
+func Test_Router_006(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{CacheSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add routes whilst serving requests for random paths
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			prefix := fmt.Sprint("/", i)
+			if err := router.(plugin.Router).AddHandler(Gateway(prefix), nil, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(prefix))
+			}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			w := httptest.NewRecorder()
+			router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprint("/", i%100, "/", i), nil))
+		}
+	}()
+	wg.Wait()
+
+	// Check every route is served, and the cache is bounded
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprint("/", i, "/test"), nil))
+		if body := w.Body.String(); body != fmt.Sprint("/", i) {
+			t.Errorf("Test %d: unexpected body: %q", i, body)
+		}
+	}
+	if str := fmt.Sprint(router); !strings.Contains(str, "cache=10/10") {
+		t.Error("Unexpected cache size in", str)
+	}
+}
+
+func Test_Router_007(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add middleware, and a gateway for '/A' and '/B'
+	a, b := Gateway("/A", "auth"), Gateway("/B")
+	if err := router.(plugin.Router).AddMiddleware("auth", func(fn http.HandlerFunc) http.HandlerFunc {
+		return fn
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, gateway := range []plugin.Gateway{a, b} {
+		prefix := gateway.Prefix()
+		if err := router.(plugin.Router).AddHandler(gateway, nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(prefix))
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	code := func(path string) int {
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	if code("/A/test") != http.StatusOK || code("/B/test") != http.StatusOK {
+		t.Error("Expected routes to be served")
+	}
+
+	// Removing middleware leaves routes which use it unresolved until it is added again
+	if err := router.(plugin.Router).RemoveMiddleware("auth"); err != nil {
+		t.Error(err)
+	} else if code := code("/A/test"); code != http.StatusInternalServerError {
+		t.Error("Unexpected status code", code)
+	}
+	if err := router.(plugin.Router).RemoveMiddleware("auth"); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+	if err := router.(plugin.Router).AddMiddleware("auth", func(fn http.HandlerFunc) http.HandlerFunc {
+		return fn
+	}); err != nil {
+		t.Error(err)
+	} else if code := code("/A/test"); code != http.StatusOK {
+		t.Error("Unexpected status code", code)
+	}
+
+	// Removing a gateway removes only the routes it added
+	if err := router.(plugin.Router).RemoveHandler(a); err != nil {
+		t.Error(err)
+	}
+	if code := code("/A/test"); code != http.StatusNotFound {
+		t.Error("Unexpected status code", code)
+	}
+	if code := code("/B/test"); code != http.StatusOK {
+		t.Error("Unexpected status code", code)
+	}
+	if err := router.(plugin.Router).RemoveHandler(a); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func Test_Router_008(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests are not blocked by a subscriber which does not receive events
+	ch := router.Sub()
+	defer router.Unsub(ch)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			router.(http.Handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Requests blocked by subscriber")
+	}
+	if n := len(ch); n != 10 {
+		t.Error("Unexpected number of events", n)
+	}
+}
+
+func Test_Router_009(t *testing.T) {
+	// Create a provider, register http server and router
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the router
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- router.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Adding a route with unknown middleware fails once the router is running
+	time.Sleep(50 * time.Millisecond)
+	if err := router.(plugin.Router).AddHandler(Gateway("/A", "unknown"), nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("/A"))
+	}); !errors.Is(err, ErrNotFound) {
+		t.Fatal("Expected ErrNotFound, got", err)
+	}
+
+	// The route is not added
+	w := httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/A", nil))
+	if w.Code != http.StatusNotFound {
+		t.Error("Unexpected status", w.Code)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// BENCHMARKS
+
+// Benchmark requests for a single path, which are served from the cache
+func Benchmark_Router_Cached(b *testing.B) {
+	router := benchmarkRouter(b)
+	req := httptest.NewRequest(http.MethodGet, "/50/test", nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+// Benchmark requests for distinct paths, which are served with a linear
+// scan of the routes
+func Benchmark_Router_Scan(b *testing.B) {
+	router := benchmarkRouter(b)
+	reqs := make([]*http.Request, 10000)
+	for i := range reqs {
+		reqs[i] = httptest.NewRequest(http.MethodGet, fmt.Sprint("/", i%100, "/test/", i), nil)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), reqs[i%len(reqs)])
+	}
+}
+
+// benchmarkRouter returns a router with one hundred prefixes, each with
+// a regular expression route
+func benchmarkRouter(b *testing.B) http.Handler {
+	p := provider.New()
+	router, err := p.New(context.Background(), Config{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	re := regexp.MustCompile("^/(test)")
+	for i := 0; i < 100; i++ {
+		if err := router.(plugin.Router).AddHandler(Gateway(fmt.Sprint("/", i)), re, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return router.(http.Handler)
+}
+
 /////////////////////////////////////////////////////////////////////
 // TASK
 
 type task struct {
-	prefix string
+	event.PubSub
+	prefix     string
+	middleware []string
 }
 
-func Gateway(prefix string) plugin.Gateway {
-	return &task{prefix: prefix}
+func Gateway(prefix string, middleware ...string) plugin.Gateway {
+	return &task{prefix: prefix, middleware: middleware}
 }
 
 func (t *task) Prefix() string {
@@ -127,7 +498,7 @@ func (t *task) Label() string {
 }
 
 func (t *task) Middleware() []string {
-	return nil
+	return t.middleware
 }
 
 // Run is called to start the task and block until context is cancelled
@@ -135,9 +506,3 @@ func (t *task) Run(ctx context.Context) error {
 	<-ctx.Done()
 	return ctx.Err()
 }
-
-// C returns a channel on which events can be received, or returns nil
-// if the task does not emit events
-func (t *task) C() <-chan Event {
-	return nil
-}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"context"
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Run checks all routes have their middleware resolved, so that unknown
+// middleware fails at startup, and then runs until the context is cancelled.
+// Whilst running, handlers with unknown middleware cannot be added
+func (r *router) Run(ctx context.Context) error {
+	r.Lock()
+	err := r.resolve()
+	r.running = err == nil
+	r.Unlock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		r.Lock()
+		r.running = false
+		r.Unlock()
+	}()
+	return r.Task.Run(ctx)
+}
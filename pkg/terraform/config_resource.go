@@ -0,0 +1,171 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	// Modules
+	path "github.com/hashicorp/terraform-plugin-framework/path"
+	resource "github.com/hashicorp/terraform-plugin-framework/resource"
+	schema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	booldefault "github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	planmodifier "github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	stringplanmodifier "github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	types "github.com/hashicorp/terraform-plugin-framework/types"
+	client "github.com/mutablelogic/terraform-provider-nginx/pkg/client"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type configResource struct {
+	client *client.Client
+}
+
+type configResourceModel struct {
+	Name    types.String `tfsdk:"name"`
+	Body    types.String `tfsdk:"body"`
+	Enabled types.Bool   `tfsdk:"enabled"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewConfigResource returns the nginx_config resource
+func NewConfigResource() resource.Resource {
+	return new(configResource)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (r *configResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_config"
+}
+
+func (r *configResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "An nginx configuration file, which can be enabled or disabled",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Description:   "Name of the configuration",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"body": schema.StringAttribute{
+				Description:   "Body of the configuration",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"enabled": schema.BoolAttribute{
+				Description: "Whether the configuration is enabled. Defaults to true",
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+			},
+		},
+	}
+}
+
+func (r *configResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	} else if client, ok := req.ProviderData.(*client.Client); !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("%T", req.ProviderData))
+	} else {
+		r.client = client
+	}
+}
+
+func (r *configResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var model configResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Create the configuration
+	config, err := r.client.CreateConfig(ctx, model.Name.ValueString(), model.Body.ValueString(), model.Enabled.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create configuration", err.Error())
+		return
+	}
+
+	// Set state
+	resp.Diagnostics.Append(resp.State.Set(ctx, modelFromConfig(config))...)
+}
+
+func (r *configResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model configResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Remove the resource from state if it no longer exists
+	config, err := r.client.GetConfig(ctx, model.Name.ValueString())
+	if errors.Is(err, ErrNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	} else if err != nil {
+		resp.Diagnostics.AddError("Unable to read configuration", err.Error())
+		return
+	}
+
+	// Set state
+	resp.Diagnostics.Append(resp.State.Set(ctx, modelFromConfig(config))...)
+}
+
+func (r *configResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var model configResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only the enabled state can be changed in place
+	if _, err := r.client.PatchConfig(ctx, model.Name.ValueString(), model.Enabled.ValueBool()); err != nil {
+		resp.Diagnostics.AddError("Unable to update configuration", err.Error())
+		return
+	}
+
+	// Read back the configuration and set state
+	config, err := r.client.GetConfig(ctx, model.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read configuration", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, modelFromConfig(config))...)
+}
+
+func (r *configResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var model configResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ignore configurations which have already been removed
+	if err := r.client.DeleteConfig(ctx, model.Name.ValueString()); err != nil && !errors.Is(err, ErrNotFound) {
+		resp.Diagnostics.AddError("Unable to delete configuration", err.Error())
+	}
+}
+
+func (r *configResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func modelFromConfig(config *client.Config) configResourceModel {
+	return configResourceModel{
+		Name:    types.StringValue(config.Name),
+		Body:    types.StringValue(config.Body),
+		Enabled: types.BoolValue(config.Enabled),
+	}
+}
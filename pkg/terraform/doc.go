@@ -0,0 +1,3 @@
+// terraform package implements a terraform provider, which manages nginx
+// configurations through the nginx-gateway API
+package terraform
@@ -0,0 +1,109 @@
+package terraform
+
+import (
+	"context"
+	"os"
+
+	// Modules
+	datasource "github.com/hashicorp/terraform-plugin-framework/datasource"
+	provider "github.com/hashicorp/terraform-plugin-framework/provider"
+	schema "github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	resource "github.com/hashicorp/terraform-plugin-framework/resource"
+	types "github.com/hashicorp/terraform-plugin-framework/types"
+	client "github.com/mutablelogic/terraform-provider-nginx/pkg/client"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type nginxProvider struct {
+	version string
+}
+
+type nginxProviderModel struct {
+	Endpoint types.String `tfsdk:"endpoint"`
+	Token    types.String `tfsdk:"token"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ProviderName = "nginx"
+	EnvEndpoint  = "NGINX_GATEWAY_ENDPOINT"
+	EnvToken     = "NGINX_GATEWAY_TOKEN"
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// New returns a function which creates the provider, with a version
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &nginxProvider{version: version}
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (p *nginxProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = ProviderName
+	resp.Version = p.version
+}
+
+func (p *nginxProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages nginx configurations through the nginx-gateway API",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "URL of the nginx-gateway API, for example http://localhost/nginx/v1. Defaults to the " + EnvEndpoint + " environment variable",
+				Optional:    true,
+			},
+			"token": schema.StringAttribute{
+				Description: "Token used to authenticate with the gateway. Defaults to the " + EnvToken + " environment variable",
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (p *nginxProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var model nginxProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set values from the environment when not set in configuration
+	endpoint, token := os.Getenv(EnvEndpoint), os.Getenv(EnvToken)
+	if !model.Endpoint.IsNull() {
+		endpoint = model.Endpoint.ValueString()
+	}
+	if !model.Token.IsNull() {
+		token = model.Token.ValueString()
+	}
+
+	// Create the client. The endpoint is the URL of the nginx-gateway, so
+	// there is no additional prefix
+	client, err := client.New(endpoint, client.OptToken(token), client.OptNginxPrefix(""))
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid endpoint", err.Error())
+		return
+	}
+
+	// Pass the client to resources
+	resp.ResourceData = client
+	resp.DataSourceData = client
+}
+
+func (p *nginxProvider) Resources(context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		NewConfigResource,
+	}
+}
+
+func (p *nginxProvider) DataSources(context.Context) []func() datasource.DataSource {
+	return nil
+}
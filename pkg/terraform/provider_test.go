@@ -0,0 +1,155 @@
+package terraform_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Modules
+	providerserver "github.com/hashicorp/terraform-plugin-framework/providerserver"
+	tfprotov6 "github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	resource "github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	client "github.com/mutablelogic/terraform-provider-nginx/pkg/client"
+	nginx "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx"
+	gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/nginx-gateway"
+	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
+	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
+	tokenauth_gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth-gateway"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx/pkg/terraform"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TESTS
+
+func Test_Provider_001(t *testing.T) {
+	endpoint, token := NewGateway(t)
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			ProviderName: providerserver.NewProtocol6WithError(New("test")()),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: ConfigResource(endpoint, token, "test", true),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("nginx_config.test", "name", "test"),
+					resource.TestCheckResourceAttr("nginx_config.test", "enabled", "true"),
+				),
+			},
+			{
+				Config: ConfigResource(endpoint, token, "test", false),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("nginx_config.test", "enabled", "false"),
+				),
+			},
+			{
+				ResourceName:                         "nginx_config.test",
+				ImportState:                          true,
+				ImportStateId:                        "test",
+				ImportStateVerify:                    true,
+				ImportStateVerifyIdentifierAttribute: "name",
+			},
+		},
+	})
+}
+
+func Test_Provider_002(t *testing.T) {
+	endpoint, token := NewGateway(t)
+	ctx := context.Background()
+
+	// Requests without a token should be rejected by the gateway
+	if c, err := client.New(endpoint, client.OptNginxPrefix("")); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.ListConfigs(ctx); !errors.Is(err, client.ErrNotAuthorized) {
+		t.Error("Expected ErrNotAuthorized, got", err)
+	}
+	if resp, err := http.Get(endpoint + "/"); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusUnauthorized {
+		t.Error("Unexpected status code", resp.StatusCode)
+	}
+
+	// Requests with the token should succeed
+	if c, err := client.New(endpoint, client.OptToken(token), client.OptNginxPrefix("")); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.ListConfigs(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// NewGateway runs an nginx-gateway in-process behind token authentication,
+// backed by a temporary folder, and returns the endpoint for the gateway and
+// a token which can read and write configurations
+func NewGateway(t *testing.T) (string, string) {
+	tmpdir := t.TempDir()
+	for _, path := range []string{"sites-available", "sites-enabled"} {
+		if err := os.Mkdir(filepath.Join(tmpdir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create tasks
+	ctx := context.Background()
+	provider := provider.New()
+	nginx, err := provider.New(ctx, nginx.Config{Path: tmpdir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := provider.New(ctx, tokenauth.Config{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.New(ctx, tokenauth_gateway.Config{Auth: types.Task{Task: auth}, Router: types.Task{Task: router}}); err != nil {
+		t.Fatal(err)
+	}
+	reload := false
+	gateway, err := provider.New(ctx, gateway.Config{Nginx: types.Task{Task: nginx}, Router: types.Task{Task: router}, Reload: &reload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serve the router
+	server := httptest.NewServer(router.(http.Handler))
+	t.Cleanup(server.Close)
+
+	// Create a token for the provider
+	token, err := auth.(TokenAuth).Create("terraform", time.Time{}, "nginx:write")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Return the endpoint and token
+	return server.URL + gateway.(Gateway).Prefix(), token
+}
+
+func ConfigResource(endpoint, token, name string, enabled bool) string {
+	return fmt.Sprintf(`
+provider "nginx" {
+	endpoint = %q
+	token    = %q
+}
+
+resource "nginx_config" %q {
+	name    = %q
+	body    = "server { listen 8080; }"
+	enabled = %v
+}
+`, endpoint, token, name, name, enabled)
+}
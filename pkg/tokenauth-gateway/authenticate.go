@@ -1,20 +1,100 @@
 package tokenauth_gateway
 
 import (
-	"fmt"
 	"net/http"
+	"strings"
+
+	// Modules
+	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 )
 
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	authorizationKey   = "Authorization"
+	authenticateKey    = "WWW-Authenticate"
+	authorizationToken = "Bearer"
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// AuthenticateHandler is middleware which checks for a bearer token, and sets
+// the name of the token and whether it has admin access in the request context.
+// Returns 401 if the token is missing or does not match, and 403 if the scopes
+// of the token do not allow the method on the route
 func (plugin *gateway) AuthenticateHandler(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("TODO: AuthenticateHandler")
-		fn(w, r)
+		if r, ok := plugin.authenticate(w, r); ok {
+			fn(w, r)
+		}
 	}
 }
 
+// AuthenticateAdminHandler is middleware which checks for a bearer token, and
+// returns 403 if the token is not the admin token
 func (plugin *gateway) AuthenticateAdminHandler(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("TODO: AuthenticateAdminHandler")
-		fn(w, r)
+		if r, ok := plugin.authenticate(w, r); !ok {
+			return
+		} else if !context.ReqAdmin(r) {
+			util.ServeError(w, http.StatusForbidden, "admin token required")
+		} else {
+			fn(w, r)
+		}
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// authenticate returns the request with the token name and admin flag set
+// in the context, or serves an error and returns false. The admin token has
+// access to all routes, and tokens without scopes have access to all routes
+// without admin access
+func (plugin *gateway) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	value, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set(authenticateKey, authorizationToken)
+		util.ServeError(w, http.StatusUnauthorized, "missing bearer token")
+		return nil, false
+	}
+	name := plugin.auth.Matches(value)
+	if name == "" {
+		w.Header().Set(authenticateKey, authorizationToken)
+		util.ServeError(w, http.StatusUnauthorized, "invalid bearer token")
+		return nil, false
+	}
+
+	// Check scopes for the route
+	admin := name == tokenauth.AdminToken
+	if scopes := plugin.auth.Scopes(name); !admin && len(scopes) > 0 {
+		var allowed bool
+		if allowed, admin = tokenauth.Allows(scopes, context.ReqPrefix(r), r.Method); !allowed {
+			util.ServeError(w, http.StatusForbidden, "insufficient scope for", r.Method, context.ReqPrefix(r))
+			return nil, false
+		}
+	}
+
+	// Set name and admin flag, and record the name on the response for
+	// middleware which wraps the response
+	util.SetName(w, name)
+	ctx := context.WithAdmin(context.WithName(r.Context(), name), admin)
+	return r.WithContext(ctx), true
+}
+
+// bearerToken returns the token from an "Authorization: Bearer <value>" header.
+// The scheme is case-insensitive
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, value, ok := strings.Cut(r.Header.Get(authorizationKey), " ")
+	if !ok || !strings.EqualFold(scheme, authorizationToken) {
+		return "", false
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return "", false
 	}
+	return value, true
 }
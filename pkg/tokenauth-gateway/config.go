@@ -4,8 +4,8 @@ import (
 	"context"
 
 	// Module imports
-
-	"github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
@@ -18,10 +18,12 @@ import (
 // TYPES
 
 type Config struct {
-	Label  string `json:"label,omitempty"`
-	Prefix string `json:"prefix,omitempty"`
-	Auth   Task   `json:"-"` // plugin.TokenAuth
-	Router Task   `json:"-"` // plugin.Router
+	Label_     string        `hcl:"label,label" json:"label,omitempty"`
+	Prefix     string        `hcl:"prefix,optional" json:"prefix,omitempty"`
+	Middleware []string      `hcl:"middleware,optional" json:"middleware,omitempty"` // Middleware applied to handlers, defaults to token authentication
+	Auth       types.Task    `hcl:"auth,optional" json:"auth"`                       // plugin.TokenAuth
+	Router     types.Task    `hcl:"router,optional" json:"router"`                   // plugin.Router
+	Policy     *types.Policy `hcl:"policy,block" json:"policy,omitempty"`            // Supervision policy for the task
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -40,24 +42,24 @@ const (
 
 func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 	// Check arguments
-	if _, ok := c.Router.(Router); c.Router == nil || !ok {
+	if _, ok := c.Router.Task.(Router); c.Router.Task == nil || !ok {
 		return nil, ErrBadParameter.With("router")
 	}
-	if _, ok := c.Auth.(TokenAuth); c.Auth == nil || !ok {
+	if _, ok := c.Auth.Task.(TokenAuth); c.Auth.Task == nil || !ok {
 		return nil, ErrBadParameter.With("auth")
 	}
 
 	// Set confuguration defaults
-	if c.Label == "" {
-		c.Label = c.Auth.Label() + DefaultLabelSuffix
-	}
 	if c.Prefix == "" {
-		c.Prefix = "/" + c.Auth.Label() + DefaultPathSuffix
+		c.Prefix = "/" + tokenauth.DefaultLabel + DefaultPathSuffix
+	}
+	if c.Middleware == nil {
+		c.Middleware = []string{MiddlewareName}
 	}
 
 	// Check parameters
-	if !util.IsIdentifier(c.Label) {
-		return nil, ErrBadParameter.Withf("label: %q", c.Label)
+	if !util.IsIdentifier(c.Label()) {
+		return nil, ErrBadParameter.Withf("label: %q", c.Label())
 	}
 
 	// Return new task
@@ -67,3 +69,11 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 func (c Config) Name() string {
 	return DefaultLabel
 }
+
+func (c Config) Label() string {
+	if c.Label_ == "" {
+		return DefaultLabel
+	} else {
+		return c.Label_
+	}
+}
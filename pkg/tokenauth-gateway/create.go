@@ -1,13 +1,36 @@
 package tokenauth_gateway
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	// Modules
 	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
 )
 
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Request is the optional body of a request to create a token
+type Request struct {
+	Scopes  []string  `json:"scopes,omitempty"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// CreateHandler creates a token, and returns the value in the response. A token
+// with scopes can only create tokens with scopes it covers, so that it cannot
+// create a token with more access than itself
 func (plugin *gateway) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	admin := context.ReqAdmin(r)
 	params := context.ReqParams(r)
@@ -16,14 +39,29 @@ func (plugin *gateway) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !admin {
-		util.ServeError(w, http.StatusUnauthorized)
+		util.ServeError(w, http.StatusForbidden, "admin token required")
+		return
+	}
+
+	// Decode the request, which can be empty
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		util.ServeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Check the scopes are covered by the scopes of the caller
+	if caller := context.ReqName(r); caller != tokenauth.AdminToken && !tokenauth.Covers(plugin.auth.Scopes(caller), req.Scopes) {
+		util.ServeError(w, http.StatusForbidden, "scopes exceed those of", caller)
 		return
 	}
 
 	name := params[0]
-	if plugin.Exists(name) {
+	if plugin.auth.Exists(name) {
 		util.ServeError(w, http.StatusBadRequest)
-	} else if value, err := plugin.Create(name); err != nil {
+	} else if value, err := plugin.auth.Create(name, req.Expires, req.Scopes...); errors.Is(err, ErrBadParameter) {
+		util.ServeError(w, http.StatusBadRequest, err.Error())
+	} else if err != nil {
 		util.ServeError(w, http.StatusInternalServerError, err.Error())
 	} else {
 		util.ServeJSON(w, value, http.StatusCreated, 2)
@@ -1,7 +1,9 @@
 // tokenauth_gateway plugin is a gateway for token authentication. It provides:
 //
-//   - Middleware for token authentication, which checks for a "Authorization: Token <value>"
-//     http header and validates against the tokenauth plugin;
-//   - HTTP handlers for creating and revoking tokens;
+//   - Middleware for token authentication, which checks for a "Authorization: Bearer <value>"
+//     http header and validates against the tokenauth plugin, and the scopes of the
+//     token against the prefix and method of the route;
+//   - HTTP handlers for creating and revoking tokens, where a token with scopes can only
+//     create tokens with scopes it covers;
 //   - Methods which can return a token name from a token value.
 package tokenauth_gateway
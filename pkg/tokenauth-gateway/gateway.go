@@ -5,19 +5,23 @@ import (
 	"net/http"
 	"regexp"
 
+	// Module imports
+	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+
 	// Namespace imports
 	. "github.com/mutablelogic/terraform-provider-nginx"
 	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
-
-	// Module imports
-	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 )
 
 /////////////////////////////////////////////////////////////////////
 // TYPES
 
 type gateway struct {
-	TokenAuth
+	event.PubSub
+
+	auth          TokenAuth
+	router        Router
 	label, prefix string
 	middleware    []string
 }
@@ -35,26 +39,29 @@ var (
 
 func NewWithConfig(c Config) (Task, error) {
 	plugin := new(gateway)
-	plugin.label = c.Label
+	plugin.label = c.Label()
 	plugin.prefix = c.Prefix
-	plugin.TokenAuth = c.Auth.(TokenAuth)
+	plugin.middleware = c.Middleware
+	plugin.auth = c.Auth.Task.(TokenAuth)
 
 	// Register middleware
-	if err := c.Router.(Router).AddMiddleware(MiddlewareName, plugin.AuthenticateHandler); err != nil {
+	router := c.Router.Task.(Router)
+	plugin.router = router
+	if err := router.AddMiddleware(MiddlewareName, plugin.AuthenticateHandler); err != nil {
 		return nil, err
 	}
-	if err := c.Router.(Router).AddMiddleware(MiddlewareAdminName, plugin.AuthenticateAdminHandler); err != nil {
+	if err := router.AddMiddleware(MiddlewareAdminName, plugin.AuthenticateAdminHandler); err != nil {
 		return nil, err
 	}
 
 	// Register handlers
-	if err := c.Router.(Router).AddHandler(c.Prefix, rePathList, plugin.ListHandler, http.MethodGet); err != nil {
+	if err := router.AddHandler(plugin, rePathList, plugin.ListHandler, http.MethodGet); err != nil {
 		return nil, err
 	}
-	if err := c.Router.(Router).AddHandler(c.Prefix, rePathCreateRevoke, plugin.CreateHandler, http.MethodPost); err != nil {
+	if err := router.AddHandler(plugin, rePathCreateRevoke, plugin.CreateHandler, http.MethodPost); err != nil {
 		return nil, err
 	}
-	if err := c.Router.(Router).AddHandler(c.Prefix, rePathCreateRevoke, plugin.RevokeHandler, http.MethodDelete); err != nil {
+	if err := router.AddHandler(plugin, rePathCreateRevoke, plugin.RevokeHandler, http.MethodDelete); err != nil {
 		return nil, err
 	}
 
@@ -69,6 +76,9 @@ func (plugin *gateway) String() string {
 	str := "<tokenauth-gateway"
 	str += fmt.Sprintf(" label=%q", plugin.label)
 	str += fmt.Sprintf(" prefix=%q", plugin.prefix)
+	if len(plugin.middleware) > 0 {
+		str += fmt.Sprintf(" middleware=%q", plugin.middleware)
+	}
 	return str + ">"
 }
 
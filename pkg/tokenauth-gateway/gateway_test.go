@@ -1,18 +1,25 @@
 package tokenauth_gateway_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	// Module imports
 	provider "github.com/mutablelogic/terraform-provider-nginx/pkg/provider"
 	router "github.com/mutablelogic/terraform-provider-nginx/pkg/router"
 	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
 	gateway "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth-gateway"
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
 	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
@@ -41,7 +48,7 @@ func Test_TokenAuthGateway_001(t *testing.T) {
 	} else {
 		t.Log(router)
 	}
-	gateway, err := provider.New(ctx, gateway.Config{Auth: tokenauth, Router: router})
+	gateway, err := provider.New(ctx, gateway.Config{Auth: types.Task{Task: tokenauth}, Router: types.Task{Task: router}})
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -59,6 +66,7 @@ func Test_TokenAuthGateway_002(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer os.RemoveAll(path)
+	admin := AdminToken(t, path)
 
 	tokenauth, err := provider.New(ctx, tokenauth.Config{Path: path})
 	if err != nil {
@@ -68,7 +76,7 @@ func Test_TokenAuthGateway_002(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	gateway, err := provider.New(ctx, gateway.Config{Auth: tokenauth, Router: router})
+	gateway, err := provider.New(ctx, gateway.Config{Auth: types.Task{Task: tokenauth}, Router: types.Task{Task: router}})
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -78,7 +86,7 @@ func Test_TokenAuthGateway_002(t *testing.T) {
 	// Check /list method
 	t.Run("List", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, gateway.(Gateway).Prefix()+"/", nil))
+		router.(http.Handler).ServeHTTP(w, Request(http.MethodGet, gateway.(Gateway).Prefix()+"/", admin))
 		if status := w.Result().StatusCode; status != http.StatusOK {
 			t.Error("unexpected status code: ", status)
 		} else {
@@ -90,7 +98,7 @@ func Test_TokenAuthGateway_002(t *testing.T) {
 	t.Run("Create", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		name := "token"
-		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodPost, gateway.(Gateway).Prefix()+"/"+name, nil))
+		router.(http.Handler).ServeHTTP(w, Request(http.MethodPost, gateway.(Gateway).Prefix()+"/"+name, admin))
 		if status := w.Result().StatusCode; status != http.StatusCreated {
 			t.Error("unexpected status code: ", status)
 			body, _ := io.ReadAll(w.Result().Body)
@@ -104,7 +112,7 @@ func Test_TokenAuthGateway_002(t *testing.T) {
 	t.Run("Revoke", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		name := "admin"
-		router.(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, gateway.(Gateway).Prefix()+"/"+name, nil))
+		router.(http.Handler).ServeHTTP(w, Request(http.MethodDelete, gateway.(Gateway).Prefix()+"/"+name, admin))
 		if status := w.Result().StatusCode; status != http.StatusOK {
 			t.Error("unexpected status code: ", status)
 		} else {
@@ -113,3 +121,287 @@ func Test_TokenAuthGateway_002(t *testing.T) {
 		}
 	})
 }
+
+func Test_TokenAuthGateway_003(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+	path := t.TempDir()
+	admin := AdminToken(t, path)
+
+	tokenauth, err := provider.New(ctx, tokenauth.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway, err := provider.New(ctx, gateway.Config{Auth: types.Task{Task: tokenauth}, Router: types.Task{Task: router}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a user token
+	user, err := tokenauth.(TokenAuth).Create("user", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := gateway.(Gateway).Prefix()
+	tests := []struct {
+		Method, Path, Authorization string
+		Code                        int
+	}{
+		{http.MethodGet, prefix + "/", "", http.StatusUnauthorized},
+		{http.MethodGet, prefix + "/", "Bearer", http.StatusUnauthorized},
+		{http.MethodGet, prefix + "/", "Token " + admin, http.StatusUnauthorized},
+		{http.MethodGet, prefix + "/", "Bearer invalid", http.StatusUnauthorized},
+		{http.MethodGet, prefix + "/", "Bearer " + user, http.StatusOK},
+		{http.MethodGet, prefix + "/", "bearer " + admin, http.StatusOK},
+		{http.MethodPost, prefix + "/test", "Bearer " + user, http.StatusForbidden},
+		{http.MethodPost, prefix + "/test", "Bearer " + admin, http.StatusCreated},
+		{http.MethodDelete, prefix + "/test", "Bearer " + user, http.StatusForbidden},
+		{http.MethodDelete, prefix + "/test", "Bearer " + admin, http.StatusOK},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.Method, test.Path, nil)
+		if test.Authorization != "" {
+			req.Header.Set("Authorization", test.Authorization)
+		}
+		router.(http.Handler).ServeHTTP(w, req)
+		if status := w.Result().StatusCode; status != test.Code {
+			t.Errorf("Test %d: unexpected status code: %v", i, status)
+		} else if status == http.StatusUnauthorized && test.Method == http.MethodGet && w.Result().Header.Get("WWW-Authenticate") == "" {
+			// Requests rejected by the middleware should include the challenge
+			t.Errorf("Test %d: missing WWW-Authenticate header", i)
+		}
+	}
+
+	// The name of the token is recorded on the response
+	w := util.NewResponseWriter(httptest.NewRecorder())
+	router.(http.Handler).ServeHTTP(w, Request(http.MethodGet, prefix+"/", user))
+	if w.Name != "user" {
+		t.Errorf("Unexpected token name: %q", w.Name)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// AdminToken writes a tokens file with a plaintext admin token, which
+// is migrated when the tokens are read, and returns the value
+func AdminToken(t *testing.T, path string) string {
+	value := "admin-secret"
+	data, err := json.Marshal(map[string]any{tokenauth.AdminToken: map[string]string{"token": value}})
+	if err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(path, tokenauth.DefaultLabel+".json"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// Request returns a request with a bearer token
+func Request(method, path, token string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func Test_TokenAuthGateway_004(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+	path := t.TempDir()
+	admin := AdminToken(t, path)
+
+	tokenauth, err := provider.New(ctx, tokenauth.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway, err := provider.New(ctx, gateway.Config{Auth: types.Task{Task: tokenauth}, Router: types.Task{Task: router}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create scoped tokens with the admin token
+	prefix := gateway.(Gateway).Prefix()
+	scopes := map[string][]string{
+		"read":   {"tokenauth:read"},
+		"admin":  {"tokenauth:admin"},
+		"nginx":  {"nginx:write"},
+		"prefix": {prefix + ":GET"},
+	}
+	tokens := make(map[string]string, len(scopes))
+	for name, scopes := range scopes {
+		var value string
+		data, _ := json.Marshal(map[string]any{"scopes": scopes})
+		req := httptest.NewRequest(http.MethodPost, prefix+"/"+name+"-token", bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+admin)
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, req)
+		if status := w.Result().StatusCode; status != http.StatusCreated {
+			t.Fatal("unexpected status code: ", status)
+		} else if err := json.NewDecoder(w.Result().Body).Decode(&value); err != nil {
+			t.Fatal(err)
+		} else {
+			tokens[name] = value
+		}
+	}
+
+	// Invalid scopes should be rejected
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, prefix+"/invalid", bytes.NewReader([]byte(`{"scopes":["nginx:invalid"]}`)))
+	req.Header.Set("Authorization", "Bearer "+admin)
+	router.(http.Handler).ServeHTTP(w, req)
+	if status := w.Result().StatusCode; status != http.StatusBadRequest {
+		t.Error("unexpected status code: ", status)
+	}
+
+	tests := []struct {
+		Method, Token string
+		Code          int
+	}{
+		{http.MethodGet, "read", http.StatusOK},
+		{http.MethodPost, "read", http.StatusForbidden},
+		{http.MethodGet, "admin", http.StatusOK},
+		{http.MethodPost, "admin", http.StatusForbidden},
+		{http.MethodGet, "nginx", http.StatusForbidden},
+		{http.MethodGet, "prefix", http.StatusOK},
+		{http.MethodDelete, "prefix", http.StatusForbidden},
+		{http.MethodDelete, "admin", http.StatusNotFound},
+	}
+	for i, test := range tests {
+		path := prefix + "/"
+		if test.Method != http.MethodGet {
+			path += "test"
+		}
+		w := httptest.NewRecorder()
+		router.(http.Handler).ServeHTTP(w, Request(test.Method, path, tokens[test.Token]))
+		if status := w.Result().StatusCode; status != test.Code {
+			t.Errorf("Test %d: unexpected status code: %v", i, status)
+		}
+	}
+
+	// Tokens which have already expired should be rejected
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, prefix+"/expired", bytes.NewReader([]byte(`{"expires":"2020-01-01T00:00:00Z"}`)))
+	req.Header.Set("Authorization", "Bearer "+admin)
+	router.(http.Handler).ServeHTTP(w, req)
+	if status := w.Result().StatusCode; status != http.StatusBadRequest {
+		t.Error("unexpected status code: ", status)
+	}
+
+	// Create a token with an expiry time
+	expires := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, prefix+"/expires", bytes.NewReader([]byte(`{"expires":"`+expires.Format(time.RFC3339)+`"}`)))
+	req.Header.Set("Authorization", "Bearer "+admin)
+	router.(http.Handler).ServeHTTP(w, req)
+	if status := w.Result().StatusCode; status != http.StatusCreated {
+		t.Error("unexpected status code: ", status)
+	}
+
+	// Scopes and expiry should be listed
+	w = httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, Request(http.MethodGet, prefix+"/", admin))
+	var result []struct {
+		Name    string    `json:"name"`
+		Scopes  []string  `json:"scopes"`
+		Expires time.Time `json:"expires"`
+	}
+	if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range result {
+		if token.Name == "read-token" && (len(token.Scopes) != 1 || token.Scopes[0] != "tokenauth:read") {
+			t.Error("Unexpected scopes: ", token)
+		}
+		if token.Name == "expires" && !token.Expires.Equal(expires) {
+			t.Error("Unexpected expiry: ", token)
+		}
+	}
+}
+
+func Test_TokenAuthGateway_005(t *testing.T) {
+	provider := provider.New()
+	ctx := context.Background()
+	path := t.TempDir()
+	admin := AdminToken(t, path)
+
+	tokenauth, err := provider.New(ctx, tokenauth.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := provider.New(ctx, router.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway, err := provider.New(ctx, gateway.Config{Auth: types.Task{Task: tokenauth}, Router: types.Task{Task: router}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a token with admin access to the tokenauth service
+	scoped, err := tokenauth.(TokenAuth).Create("scoped", time.Time{}, "tokenauth:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The scoped token cannot create a token with more access than itself
+	prefix := gateway.(Gateway).Prefix()
+	tests := []struct {
+		Name, Body string
+		Code       int
+	}{
+		{"unscoped", ``, http.StatusForbidden},
+		{"nginx", `{"scopes":["nginx:write"]}`, http.StatusForbidden},
+		{"root", `{"scopes":["/:admin"]}`, http.StatusForbidden},
+		{"mixed", `{"scopes":["tokenauth:read","nginx:read"]}`, http.StatusForbidden},
+		{"read", `{"scopes":["tokenauth:read"]}`, http.StatusCreated},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, prefix+"/"+test.Name, bytes.NewReader([]byte(test.Body)))
+		req.Header.Set("Authorization", "Bearer "+scoped)
+		router.(http.Handler).ServeHTTP(w, req)
+		if status := w.Result().StatusCode; status != test.Code {
+			t.Errorf("Test %d: unexpected status code: %v", i, status)
+		}
+	}
+
+	// The admin token can create a token without scopes
+	w := httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, Request(http.MethodPost, prefix+"/unscoped", admin))
+	if status := w.Result().StatusCode; status != http.StatusCreated {
+		t.Error("unexpected status code: ", status)
+	}
+
+	// The scoped token cannot rotate the admin token
+	w = httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, Request(http.MethodDelete, prefix+"/admin", scoped))
+	if status := w.Result().StatusCode; status != http.StatusForbidden {
+		t.Error("unexpected status code: ", status)
+	} else if tokenauth.(TokenAuth).Matches(admin) != "admin" {
+		t.Error("Expected admin token not to be rotated")
+	}
+
+	// The admin token can rotate itself, and the new value is only written
+	// to the admin file
+	w = httptest.NewRecorder()
+	router.(http.Handler).ServeHTTP(w, Request(http.MethodDelete, prefix+"/admin", admin))
+	body, _ := io.ReadAll(w.Result().Body)
+	if status := w.Result().StatusCode; status != http.StatusOK {
+		t.Error("unexpected status code: ", status)
+	} else if len(body) != 0 {
+		t.Errorf("Unexpected body: %q", body)
+	} else if value, err := os.ReadFile(filepath.Join(path, "tokenauth.admin")); err != nil {
+		t.Error(err)
+	} else if tokenauth.(TokenAuth).Matches(strings.TrimSpace(string(value))) != "admin" {
+		t.Error("Expected admin file to contain the rotated token")
+	}
+}
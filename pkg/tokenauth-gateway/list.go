@@ -15,7 +15,7 @@ type Token struct {
 
 func (plugin *gateway) ListHandler(w http.ResponseWriter, r *http.Request) {
 	// Enumerate tokens
-	tokens := plugin.Enumerate()
+	tokens := plugin.auth.Enumerate()
 	if tokens == nil {
 		util.ServeError(w, http.StatusInternalServerError)
 		return
@@ -23,8 +23,8 @@ func (plugin *gateway) ListHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create response
 	result := make([]Token, 0, len(tokens))
-	for name, time := range tokens {
-		result = append(result, Token{Name: name, Token: tokenauth.Token{Time: time}})
+	for name, info := range tokens {
+		result = append(result, Token{Name: name, Token: tokenauth.Token{Scopes: info.Scopes(), Time: info.AccessTime(), Expires: info.Expires()}})
 	}
 
 	// Serve response
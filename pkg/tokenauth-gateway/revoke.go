@@ -5,9 +5,13 @@ import (
 
 	// Modules
 	context "github.com/mutablelogic/terraform-provider-nginx/pkg/context"
+	tokenauth "github.com/mutablelogic/terraform-provider-nginx/pkg/tokenauth"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 )
 
+// RevokeHandler revokes a token. The admin token is rotated rather than
+// revoked, which requires the admin token. The new value is written to the
+// admin file, and is not returned in the response
 func (plugin *gateway) RevokeHandler(w http.ResponseWriter, r *http.Request) {
 	admin := context.ReqAdmin(r)
 	params := context.ReqParams(r)
@@ -16,14 +20,22 @@ func (plugin *gateway) RevokeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !admin {
-		util.ServeError(w, http.StatusUnauthorized)
+		util.ServeError(w, http.StatusForbidden, "admin token required")
 		return
 	}
 
 	name := params[0]
-	if !plugin.Exists(name) {
+	if name == tokenauth.AdminToken {
+		if context.ReqName(r) != tokenauth.AdminToken {
+			util.ServeError(w, http.StatusForbidden, "admin token required")
+		} else if _, err := plugin.auth.Rotate(); err != nil {
+			util.ServeError(w, http.StatusInternalServerError, err.Error())
+		} else {
+			util.ServeEmpty(w, http.StatusOK)
+		}
+	} else if !plugin.auth.Exists(name) {
 		util.ServeError(w, http.StatusNotFound)
-	} else if err := plugin.Revoke(name); err != nil {
+	} else if err := plugin.auth.Revoke(name); err != nil {
 		util.ServeError(w, http.StatusInternalServerError, err.Error())
 	} else {
 		// Serve emoty page
@@ -3,16 +3,17 @@ package tokenauth_gateway
 import (
 	"context"
 
-	// Namespace imports
-	. "github.com/mutablelogic/terraform-provider-nginx"
+	// Module imports
+	multierror "github.com/hashicorp/go-multierror"
 )
 
 /////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
-// Run will write the authorization tokens back to disk if they have been modified
-func (*gateway) Run(ctx context.Context) error {
+// Run until done
+func (plugin *gateway) Run(ctx context.Context) error {
 	<-ctx.Done()
+	plugin.Emit(nil)
 	return ctx.Err()
 }
 
@@ -20,6 +21,17 @@ func (plugin *gateway) Label() string {
 	return plugin.label
 }
 
-func (*gateway) C() <-chan Event {
-	return nil
+// Close removes the handlers and middleware from the router, when the task
+// is removed from the provider
+func (plugin *gateway) Close() error {
+	var result error
+	if err := plugin.router.RemoveHandler(plugin); err != nil {
+		result = multierror.Append(result, err)
+	}
+	for _, name := range []string{MiddlewareName, MiddlewareAdminName} {
+		if err := plugin.router.RemoveMiddleware(name); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	// Module imports
+	types "github.com/mutablelogic/terraform-provider-nginx/pkg/types"
 	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
 
 	// Namespace imports
@@ -18,10 +19,12 @@ import (
 // TYPES
 
 type Config struct {
-	Label string        `json:"label,omitempty"`
-	Path  string        `json:"path,omitempty"`
-	File  string        `json:"file,omitempty"`
-	Delta time.Duration `json:"delta,omitempty"`
+	Label_ string        `hcl:"label,label" json:"label,omitempty"`
+	Path   string        `hcl:"path,optional" json:"path,omitempty"`
+	File   string        `hcl:"file,optional" json:"file,omitempty"`
+	Delta  time.Duration `hcl:"delta,optional" json:"delta,omitempty"`
+	Idle   time.Duration `hcl:"idle_timeout,optional" json:"idle_timeout,omitempty"` // Tokens not used within the timeout expire, except the admin token
+	Policy *types.Policy `hcl:"policy,block" json:"policy,omitempty"`                // Supervision policy for the task
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -34,6 +37,7 @@ const (
 
 const (
 	defaultFile                 = DefaultLabel + ".json"
+	adminFileExt                = ".admin"
 	defaultLength               = 32
 	defaultDelta                = time.Second * 30
 	defaultEventChannelCapacity = 1000
@@ -44,19 +48,19 @@ const (
 
 func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 	// Set confuguration defaults
-	if c.Label == "" {
-		c.Label = DefaultLabel
-	}
 	if c.File == "" {
 		c.File = defaultFile
 	}
 	if c.Delta <= 0 {
 		c.Delta = defaultDelta
 	}
+	if c.Idle < 0 {
+		return nil, ErrBadParameter.Withf("idle_timeout: %v", c.Idle)
+	}
 
 	// Check label is valid
-	if !util.IsIdentifier(c.Label) {
-		return nil, ErrBadParameter.Withf("label: %q", c.Label)
+	if !util.IsIdentifier(c.Label()) {
+		return nil, ErrBadParameter.Withf("label: %q", c.Label())
 	}
 
 	// If path is empty, then use the default and maybe create it
@@ -64,7 +68,7 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 		if path, err := os.UserConfigDir(); err != nil {
 			return nil, err
 		} else {
-			c.Path = filepath.Join(path, c.Label)
+			c.Path = filepath.Join(path, c.Label())
 		}
 		if err := os.MkdirAll(c.Path, 0755); err != nil {
 			return nil, err
@@ -78,3 +82,11 @@ func (c Config) New(ctx context.Context, provider Provider) (Task, error) {
 func (c Config) Name() string {
 	return DefaultLabel
 }
+
+func (c Config) Label() string {
+	if c.Label_ == "" {
+		return DefaultLabel
+	} else {
+		return c.Label_
+	}
+}
@@ -1,2 +1,8 @@
-// tokenauth package manages the authentication tokens
+// tokenauth package manages the authentication tokens.
+//
+// Only the hash of each token is stored. When the admin token is created
+// or rotated, its value is written to a file next to the tokens file with
+// the extension ".admin", which is only readable by the owner, and a
+// TokenRotated event is emitted with the name of the token. Token values
+// are never emitted as events.
 package tokenauth
@@ -0,0 +1,161 @@
+package tokenauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// file is the representation of the tokens on disk. The salt is
+// generated once per file, and each token value is stored as a
+// HMAC-SHA256 of the value keyed with the salt
+type file struct {
+	Salt   string            `json:"salt"`
+	Tokens map[string]*Token `json:"tokens"`
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	defaultSaltLength = 32
+	fileMode          = 0600
+)
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// hash returns the salted hash of a token value
+func hash(salt []byte, value string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fileRead reads the tokens from disk. If the file does not exist, then a new salt
+// is generated. Files which store plaintext token values (a map of token name to token)
+// are migrated, and true is returned to indicate the file should be written back
+func fileRead(filename string) (*file, bool, error) {
+	// If the file doesn't exist, return empty result with a new salt
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		salt, err := generateSalt()
+		return &file{Salt: salt, Tokens: map[string]*Token{}}, true, err
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	// Determine the format of the file: the salt is a string, whereas a
+	// token in a plaintext file is an object
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false, err
+	}
+	var result file
+	var salt string
+	if err := json.Unmarshal(fields["salt"], &salt); err == nil {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, false, err
+		}
+	} else if err := json.Unmarshal(data, &result.Tokens); err != nil {
+		return nil, false, err
+	}
+	if result.Tokens == nil {
+		result.Tokens = map[string]*Token{}
+	}
+
+	// Generate a salt for plaintext files
+	migrate := false
+	if result.Salt == "" {
+		if result.Salt, err = generateSalt(); err != nil {
+			return nil, false, err
+		}
+		migrate = true
+	}
+
+	// Hash any plaintext values
+	salt_, err := hex.DecodeString(result.Salt)
+	if err != nil {
+		return nil, false, ErrBadParameter.Withf("invalid salt in %q", filename)
+	}
+	for name, token := range result.Tokens {
+		if token == nil {
+			return nil, false, ErrBadParameter.Withf("invalid token %q in %q", name, filename)
+		} else if token.Value != "" {
+			token.Hash, token.Value = hash(salt_, token.Value), ""
+			migrate = true
+		} else if token.Hash == "" {
+			return nil, false, ErrBadParameter.Withf("missing hash for token %q in %q", name, filename)
+		}
+		// Tokens without an access time would immediately expire with an idle timeout
+		if token.Time.IsZero() {
+			token.Time = time.Now()
+			migrate = true
+		}
+	}
+
+	// Return success
+	return &result, migrate, nil
+}
+
+// fileWrite writes the tokens to disk, readable only by the owner
+func fileWrite(filename string, salt []byte, tokens map[string]*Token) error {
+	if tokens == nil {
+		return ErrBadParameter.Withf("tokens is nil")
+	}
+
+	// Create the file
+	fh, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	// Files created before tokens were hashed may be readable by others
+	if err := fh.Chmod(fileMode); err != nil {
+		return err
+	}
+
+	// Write the tokens
+	if err := json.NewEncoder(fh).Encode(file{Salt: hex.EncodeToString(salt), Tokens: tokens}); err != nil {
+		return err
+	}
+
+	// Return success
+	return nil
+}
+
+// adminWrite writes the admin token value to a file which is only
+// readable by the owner, so the value is handed over without being
+// emitted as an event
+func adminWrite(filename, value string) error {
+	fh, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	if err := fh.Chmod(fileMode); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(fh, value)
+	return err
+}
+
+func generateSalt() (string, error) {
+	b := make([]byte, defaultSaltLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,241 @@
+package tokenauth
+
+import (
+	"net/http"
+	"strings"
+
+	// Module imports
+	util "github.com/mutablelogic/terraform-provider-nginx/pkg/util"
+	slices "golang.org/x/exp/slices"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Scope restricts the routes a token can access. A scope is written as
+// <resource>:<permission> where the resource is either the name of a service,
+// which matches the first path segment of a router prefix, or a router prefix
+// starting with a "/". The permission is one of "read", "write", "admin" or a
+// comma-separated list of HTTP methods. For example,
+//
+//	nginx:read             // read nginx configurations
+//	nginx:write            // read and write nginx configurations
+//	tokenauth:admin        // create and revoke tokens
+//	/nginx/v1:GET,PATCH    // read, enable and disable nginx configurations
+type Scope struct {
+	Service string   // Service name, or empty if the scope is for a prefix
+	Prefix  string   // Router prefix, or empty if the scope is for a service
+	Methods []string // HTTP methods, or nil for all methods
+	Admin   bool     // Admin access to the service or prefix
+}
+
+/////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	scopeSeparator   = ":"
+	methodSeparator  = ","
+	pathSeparator    = "/"
+	PermissionRead   = "read"
+	PermissionWrite  = "write"
+	PermissionAdmin  = "admin"
+	ScopeTokenAdmin  = DefaultLabel + scopeSeparator + PermissionAdmin
+	ScopeNginxRead   = "nginx" + scopeSeparator + PermissionRead
+	ScopeNginxWrite  = "nginx" + scopeSeparator + PermissionWrite
+	scopeReadMethods = http.MethodGet + methodSeparator + http.MethodHead + methodSeparator + http.MethodOptions
+)
+
+var (
+	// Methods which can be used in a scope
+	scopeMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}
+)
+
+/////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// ParseScope returns a scope from a string, or returns ErrBadParameter
+func ParseScope(v string) (*Scope, error) {
+	i := strings.LastIndex(v, scopeSeparator)
+	if i < 0 {
+		return nil, ErrBadParameter.Withf("scope: %q", v)
+	}
+	resource, permission := v[:i], v[i+1:]
+
+	// Parse resource
+	scope := new(Scope)
+	if strings.HasPrefix(resource, pathSeparator) {
+		scope.Prefix = normalizePrefix(resource)
+	} else if util.IsIdentifier(resource) {
+		scope.Service = resource
+	} else {
+		return nil, ErrBadParameter.Withf("scope: %q", v)
+	}
+
+	// Parse permission
+	switch permission {
+	case PermissionRead:
+		scope.Methods = strings.Split(scopeReadMethods, methodSeparator)
+	case PermissionWrite:
+		scope.Methods = nil
+	case PermissionAdmin:
+		scope.Admin = true
+	default:
+		for _, method := range strings.Split(permission, methodSeparator) {
+			if !slices.Contains(scopeMethods, method) {
+				return nil, ErrBadParameter.Withf("scope: %q", v)
+			}
+			scope.Methods = append(scope.Methods, method)
+		}
+	}
+
+	// Return success
+	return scope, nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (s *Scope) String() string {
+	str := s.Service
+	if s.Prefix != "" {
+		str = strings.TrimSuffix(s.Prefix, pathSeparator)
+	}
+	switch {
+	case s.Admin:
+		return str + scopeSeparator + PermissionAdmin
+	case s.Methods == nil:
+		return str + scopeSeparator + PermissionWrite
+	case slices.Equal(s.Methods, strings.Split(scopeReadMethods, methodSeparator)):
+		return str + scopeSeparator + PermissionRead
+	default:
+		return str + scopeSeparator + strings.Join(s.Methods, methodSeparator)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Allows returns true if the scope allows a method on a router prefix, and
+// whether the scope grants admin access
+func (s *Scope) Allows(prefix, method string) (bool, bool) {
+	prefix = normalizePrefix(prefix)
+	if s.Prefix != "" && !strings.HasPrefix(prefix, s.Prefix) {
+		return false, false
+	}
+	if s.Service != "" && service(prefix) != s.Service {
+		return false, false
+	}
+	if s.Methods != nil && !slices.Contains(s.Methods, method) {
+		return false, false
+	}
+	return true, s.Admin
+}
+
+// Allows returns true if any of the scopes allows a method on a router prefix,
+// and whether any of the allowed scopes grants admin access. Scopes which cannot
+// be parsed are ignored
+func Allows(scopes []string, prefix, method string) (bool, bool) {
+	var allowed, admin bool
+	for _, v := range scopes {
+		if scope, err := ParseScope(v); err != nil {
+			continue
+		} else if allowed_, admin_ := scope.Allows(prefix, method); allowed_ {
+			allowed, admin = true, admin || admin_
+		}
+	}
+	return allowed, admin
+}
+
+// Covers returns true if the scope grants at least the access of another
+// scope, so that a token with the scope can create a token with the other
+// scope without gaining access
+func (s *Scope) Covers(other *Scope) bool {
+	if !strings.HasPrefix(other.resource(), s.resource()) {
+		return false
+	}
+	switch {
+	case s.Admin:
+		return true
+	case other.Admin:
+		return false
+	case s.Methods == nil:
+		return true
+	case other.Methods == nil:
+		return false
+	}
+	for _, method := range other.Methods {
+		if !slices.Contains(s.Methods, method) {
+			return false
+		}
+	}
+	return true
+}
+
+// Covers returns true if every scope in other is covered by one of the scopes.
+// Returns false if other is empty, as a token without scopes can access all
+// routes, or if any scope cannot be parsed
+func Covers(scopes, other []string) bool {
+	if len(other) == 0 {
+		return false
+	}
+	for _, v := range other {
+		scope, err := ParseScope(v)
+		if err != nil {
+			return false
+		}
+		var covered bool
+		for _, v := range scopes {
+			if s, err := ParseScope(v); err == nil && s.Covers(scope) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// resource returns the router prefix which the scope applies to, where
+// a service matches the prefix with the service as the first segment
+func (s *Scope) resource() string {
+	if s.Prefix != "" {
+		return s.Prefix
+	}
+	return normalizePrefix(s.Service)
+}
+
+// normalizePrefix adds a leading and trailing separator
+func normalizePrefix(prefix string) string {
+	return pathSeparator + strings.Trim(prefix, pathSeparator) + pathSeparator
+}
+
+// service returns the first segment of a prefix
+func service(prefix string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(prefix, pathSeparator), pathSeparator)
+	return segment
+}
+
+// validateScopes returns an error if any scope cannot be parsed, and
+// returns the scopes with any duplicates removed
+func validateScopes(scopes []string) ([]string, error) {
+	var result []string
+	for _, v := range scopes {
+		if _, err := ParseScope(v); err != nil {
+			return nil, err
+		} else if !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
@@ -8,31 +8,44 @@ import (
 	event "github.com/mutablelogic/terraform-provider-nginx/pkg/event"
 
 	// Namespace imports
-	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
 )
 
 /////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
-// Run will write the authorization tokens back to disk if they have been modified
+// Run will remove expired tokens, emitting an event for each token removed, and
+// write the authorization tokens back to disk if they have been modified. When the
+// admin token has been created, a TokenRotated event is emitted with the name of
+// the token, and the value can be read from the admin file
 func (c *auth) Run(ctx context.Context) error {
-	ticker := time.NewTicker(c.delta)
+	ticker := time.NewTimer(100 * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			close(c.ch)
+			c.Lock()
 			_, err := c.writeIfModified()
+			c.Unlock()
+			c.Emit(nil)
 			return err
 		case <-ticker.C:
 			c.Lock()
+			if c.rotated {
+				c.Emit(event.NewEvent(TokenRotated, AdminToken))
+				c.rotated = false
+			}
+			for _, name := range c.prune(time.Now()) {
+				c.Emit(event.NewEvent(TokenExpired, name))
+			}
 			if written, err := c.writeIfModified(); err != nil {
-				event.NewError(err).Emit(c.ch)
+				c.Emit(event.NewError(err))
 			} else if written {
-				event.NewEvent(nil, "Written tokens to disk").Emit(c.ch)
+				c.Emit(event.NewEvent(nil, "Written tokens to disk"))
 			}
 			c.Unlock()
+			ticker.Reset(c.delta)
 		}
 	}
 }
@@ -41,8 +54,3 @@ func (c *auth) Run(ctx context.Context) error {
 func (c *auth) Label() string {
 	return c.label
 }
-
-// Return event channel
-func (c *auth) C() <-chan Event {
-	return c.ch
-}
@@ -9,8 +9,17 @@ import (
 // TYPES
 
 type Token struct {
-	Value string    `json:"token,omitempty"`
-	Time  time.Time `json:"access_time"`
+	Value   string    `json:"token,omitempty"`  // Plaintext value, only read from files which have not been migrated
+	Hash    string    `json:"hash,omitempty"`   // Salted hash of the value
+	Scopes  []string  `json:"scopes,omitempty"` // Scopes, or empty for access to all routes
+	Time    time.Time `json:"access_time"`      // Last access time
+	Expires time.Time `json:"expires,omitzero"` // Expiry time, or zero if the token does not expire
+}
+
+// info describes a token, including any idle timeout
+type info struct {
+	scopes        []string
+	time, expires time.Time
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -18,7 +27,45 @@ type Token struct {
 
 func (t *Token) String() string {
 	str := "<tokenauth-token"
-	str += fmt.Sprintf(" token=%q", t.Value)
+	if len(t.Scopes) > 0 {
+		str += fmt.Sprintf(" scopes=%q", t.Scopes)
+	}
 	str += fmt.Sprintf(" access_time=%q", t.Time.Format(time.RFC3339))
+	if !t.Expires.IsZero() {
+		str += fmt.Sprintf(" expires=%q", t.Expires.Format(time.RFC3339))
+	}
 	return str + ">"
 }
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ExpiresAt returns the time the token expires, given an idle timeout, or
+// zero if the token does not expire
+func (t *Token) ExpiresAt(idle time.Duration) time.Time {
+	expires := t.Expires
+	if idle > 0 {
+		if idle := t.Time.Add(idle); expires.IsZero() || idle.Before(expires) {
+			expires = idle
+		}
+	}
+	return expires
+}
+
+// Expired returns true if the token has expired at a time, given an idle timeout
+func (t *Token) Expired(now time.Time, idle time.Duration) bool {
+	expires := t.ExpiresAt(idle)
+	return !expires.IsZero() && !now.Before(expires)
+}
+
+func (i *info) Scopes() []string {
+	return i.scopes
+}
+
+func (i *info) AccessTime() time.Time {
+	return i.time
+}
+
+func (i *info) Expires() time.Time {
+	return i.expires
+}
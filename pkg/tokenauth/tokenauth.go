@@ -2,11 +2,12 @@ package tokenauth
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,21 +17,26 @@ import (
 
 	// Namespace imports
 	. "github.com/djthorpe/go-errors"
-	. "github.com/mutablelogic/terraform-provider-nginx"
+	. "github.com/mutablelogic/terraform-provider-nginx/plugin"
 )
 
 /////////////////////////////////////////////////////////////////////
 // TYPES
 
 type auth struct {
+	event.PubSub
 	sync.RWMutex
 
 	label    string
 	delta    time.Duration
+	idle     time.Duration
 	path     string
-	tokens   map[string]*Token
+	admin    string // Path to the file which holds the admin token value
+	salt     []byte
+	tokens   map[string]*Token // Tokens keyed by name
+	hashes   map[string]string // Token names keyed by hash
+	rotated  bool              // Set when the admin token was created, until the event is emitted
 	modified bool
-	ch       chan Event
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -38,9 +44,10 @@ type auth struct {
 
 func NewWithConfig(c Config) (*auth, error) {
 	this := new(auth)
-	this.ch = make(chan Event, defaultEventChannelCapacity)
+	this.Cap = defaultEventChannelCapacity
 	this.delta = c.Delta
-	this.label = c.Label
+	this.idle = c.Idle
+	this.label = c.Label()
 
 	// Check for path
 	if stat, err := os.Stat(c.Path); err != nil {
@@ -54,24 +61,43 @@ func NewWithConfig(c Config) (*auth, error) {
 		return nil, err
 	} else {
 		this.path = fn
+		this.admin = strings.TrimSuffix(fn, filepath.Ext(fn)) + adminFileExt
 	}
 
-	// Read the file if it exists
-	if tokens, err := fileRead(this.path); err != nil {
+	// Read the file if it exists, migrating any plaintext tokens
+	file, modified, err := fileRead(this.path)
+	if err != nil {
+		return nil, err
+	} else if salt, err := hex.DecodeString(file.Salt); err != nil {
 		return nil, err
 	} else {
-		this.tokens = tokens
+		this.salt = salt
+		this.tokens = file.Tokens
+		this.hashes = make(map[string]string, len(file.Tokens))
+	}
+	for name, token := range this.tokens {
+		this.hashes[token.Hash] = name
 	}
 
-	// If the admin token does not exist, then create it
+	// If the admin token does not exist, then create it. The value is
+	// written to the admin file, and an event is emitted when the task is run
+	var admin string
 	if _, ok := this.tokens[AdminToken]; !ok {
-		// Create a new token
-		this.tokens[AdminToken] = newToken(defaultLength)
+		admin = this.newToken(AdminToken)
+		this.rotated = true
+		modified = true
 	}
 
 	// Write tokens to disk
-	if err := fileWrite(this.path, this.tokens); err != nil {
-		return nil, err
+	if modified {
+		if err := fileWrite(this.path, this.salt, this.tokens); err != nil {
+			return nil, err
+		}
+	}
+	if admin != "" {
+		if err := adminWrite(this.admin, admin); err != nil {
+			return nil, err
+		}
 	}
 
 	// Return success
@@ -85,10 +111,14 @@ func (c *auth) String() string {
 	str := "<tokenauth"
 	str += fmt.Sprintf(" label=%q", c.label)
 	str += fmt.Sprintf(" path=%q", c.path)
+	str += fmt.Sprintf(" admin=%q", c.admin)
 	for k, v := range c.tokens {
 		str += fmt.Sprintf(" %v=%v", k, v)
 	}
 	str += fmt.Sprint(" delta=", c.delta)
+	if c.idle > 0 {
+		str += fmt.Sprint(" idle_timeout=", c.idle)
+	}
 	return str + ">"
 }
 
@@ -104,8 +134,12 @@ func (c *auth) Exists(name string) bool {
 	return ok
 }
 
-// Create a new token associated with a name and return it.
-func (c *auth) Create(name string) (string, error) {
+// Create a new token associated with a name, optional expiry time and optional
+// scopes, and return it. Only the hash of the token is stored, so the value
+// cannot be retrieved again. A token without scopes can access all routes which
+// do not require the admin token, and a token with a zero expiry time does not
+// expire unless there is an idle timeout
+func (c *auth) Create(name string, expires time.Time, scopes ...string) (string, error) {
 	c.Lock()
 	defer c.Unlock()
 
@@ -121,49 +155,51 @@ func (c *auth) Create(name string) (string, error) {
 	if name == AdminToken {
 		return "", ErrBadParameter.Withf("%q", name)
 	}
+	// If the expiry time is in the past, then return an error
+	if !expires.IsZero() && !expires.After(time.Now()) {
+		return "", ErrBadParameter.Withf("expires: %v", expires)
+	}
+	// If any scope is invalid, then return an error
+	scopes, err := validateScopes(scopes)
+	if err != nil {
+		return "", err
+	}
 
 	// Create a new token
-	c.tokens[name] = newToken(defaultLength)
+	value := c.newToken(name)
+	c.tokens[name].Scopes = scopes
+	c.tokens[name].Expires = expires
 
 	// Set modified flag
 	c.setModified(true)
 
 	// Success: return the token value
-	return c.tokens[name].Value, nil
+	return value, nil
 }
 
 // Revoke a token associated with a name. For the admin token, it is
-// rotated rather than revoked.
+// rotated rather than revoked, and the new value is written to the admin file
 func (c *auth) Revoke(name string) error {
 	c.Lock()
 	defer c.Unlock()
 
 	// If the name does not exist, then return an error
-	if _, ok := c.tokens[name]; !ok {
+	token, ok := c.tokens[name]
+	if !ok {
 		return ErrNotFound.Withf("%q", name)
 	}
 
-	// Either delete or rotate the token
-	var immediately bool
-	if name == AdminToken {
-		// Rotate the token
-		c.tokens[name] = newToken(defaultLength)
-		// Write immediately
-		immediately = true
-	} else {
-		// Delete the token
-		delete(c.tokens, name)
-	}
+	// Delete the token
+	delete(c.hashes, token.Hash)
+	delete(c.tokens, name)
 
 	// Set modified flag
 	c.setModified(true)
 
-	// Write to disk immediately when admin token is rotated
-	if immediately {
-		if written, err := c.writeIfModified(); err != nil {
+	// Rotate the admin token
+	if name == AdminToken {
+		if _, err := c.rotate(); err != nil {
 			return err
-		} else if written {
-			event.NewEvent(nil, "Admin token rotated").Emit(c.ch)
 		}
 	}
 
@@ -171,41 +207,107 @@ func (c *auth) Revoke(name string) error {
 	return nil
 }
 
-// Return all token names and their last access times
-func (c *auth) Enumerate() map[string]time.Time {
+// Rotate the admin token, and return the new value. The new value is also
+// written to the admin file, and a TokenRotated event is emitted with the
+// name of the token
+func (c *auth) Rotate() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	// Delete the admin token
+	if token, ok := c.tokens[AdminToken]; ok {
+		delete(c.hashes, token.Hash)
+		delete(c.tokens, AdminToken)
+		c.setModified(true)
+	}
+
+	// Create a new admin token
+	return c.rotate()
+}
+
+// Return all token names with their scopes, last access and expiry times
+func (c *auth) Enumerate() map[string]TokenInfo {
 	c.RLock()
 	defer c.RUnlock()
 
-	var result = make(map[string]time.Time)
+	var result = make(map[string]TokenInfo)
 	for k, v := range c.tokens {
-		result[k] = v.Time
+		result[k] = &info{v.Scopes, v.Time, v.ExpiresAt(c.idleFor(k))}
 	}
 
 	// Return the result
 	return result
 }
 
+// Return the scopes for a token, or nil if the token does not exist
+// or has no scopes
+func (c *auth) Scopes(name string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if token, exists := c.tokens[name]; exists && len(token.Scopes) > 0 {
+		return append([]string{}, token.Scopes...)
+	}
+	return nil
+}
+
 // Returns the name of the token if a value matches. Updates
 // the access time for the token. If token with value not
-// found, then return empty string
+// found, then return empty string. The token is found by
+// the hash of the value, which is then compared in constant time.
+// A TokenMatch or TokenMiss event is emitted
 func (c *auth) Matches(value string) string {
+	if name := c.matches(value); name != "" {
+		c.Emit(event.NewEvent(TokenMatch, name))
+		return name
+	} else {
+		c.Emit(event.NewEvent(TokenMiss, nil))
+		return ""
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// matches returns the name of the token if a value matches, and updates
+// the access time for the token
+func (c *auth) matches(value string) string {
+	if value == "" {
+		return ""
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
-	for k, v := range c.tokens {
-		if v.Value == value {
-			v.Time = time.Now()
-			c.setModified(true)
-			return k
-		}
+	hash := hash(c.salt, value)
+	if name, exists := c.hashes[hash]; !exists {
+		return ""
+	} else if token := c.tokens[name]; subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hash)) != 1 {
+		return ""
+	} else if token.Expired(time.Now(), c.idleFor(name)) {
+		// Expired tokens are removed when pruned
+		return ""
+	} else {
+		token.Time = time.Now()
+		c.setModified(true)
+		return name
 	}
-
-	// Token not found
-	return ""
 }
 
-/////////////////////////////////////////////////////////////////////
-// PRIVATE METHODS
+// rotate creates a new admin token, writes the tokens and the admin
+// token value to disk immediately, and returns the value
+func (c *auth) rotate() (string, error) {
+	value := c.newToken(AdminToken)
+	c.setModified(true)
+	if _, err := c.writeIfModified(); err != nil {
+		return "", err
+	}
+	if err := adminWrite(c.admin, value); err != nil {
+		return "", err
+	}
+	c.Emit(event.NewEvent(TokenRotated, AdminToken))
+	return value, nil
+}
 
 // setModified sets a new modified value, and returns true if changed
 func (c *auth) setModified(modified bool) bool {
@@ -221,7 +323,7 @@ func (c *auth) setModified(modified bool) bool {
 func (c *auth) writeIfModified() (bool, error) {
 	modified := c.setModified(false)
 	if modified {
-		if err := fileWrite(c.path, c.tokens); err != nil {
+		if err := fileWrite(c.path, c.salt, c.tokens); err != nil {
 			return modified, err
 		}
 	}
@@ -230,58 +332,41 @@ func (c *auth) writeIfModified() (bool, error) {
 	return modified, nil
 }
 
-func fileRead(filename string) (map[string]*Token, error) {
-	var result = map[string]*Token{}
-
-	// If the file doesn't exist, return empty result
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return result, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	// Open the file
-	fh, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer fh.Close()
-
-	// Decode the file
-	if err := json.NewDecoder(fh).Decode(&result); err != nil {
-		return nil, err
+// idleFor returns the idle timeout for a token. The admin token does
+// not have an idle timeout
+func (c *auth) idleFor(name string) time.Duration {
+	if name == AdminToken {
+		return 0
 	}
-
-	// Return success
-	return result, nil
+	return c.idle
 }
 
-func fileWrite(filename string, tokens map[string]*Token) error {
-	if tokens == nil {
-		return ErrBadParameter.Withf("tokens is nil")
-	}
-
-	// Create the file
-	fh, err := os.Create(filename)
-	if err != nil {
-		return err
+// prune removes expired tokens and returns their names
+func (c *auth) prune(now time.Time) []string {
+	var result []string
+	for name, token := range c.tokens {
+		if token.Expired(now, c.idleFor(name)) {
+			delete(c.hashes, token.Hash)
+			delete(c.tokens, name)
+			result = append(result, name)
+		}
 	}
-	defer fh.Close()
-
-	// Write the tokens
-	if err := json.NewEncoder(fh).Encode(tokens); err != nil {
-		return err
+	if len(result) > 0 {
+		c.setModified(true)
 	}
-
-	// Return success
-	return nil
+	return result
 }
 
-func newToken(length int) *Token {
-	return &Token{
-		Value: generateToken(length),
-		Time:  time.Now(),
+// newToken creates a token with a name, and returns the value
+func (c *auth) newToken(name string) string {
+	value := generateToken(defaultLength)
+	token := &Token{
+		Hash: hash(c.salt, value),
+		Time: time.Now(),
 	}
+	c.tokens[name] = token
+	c.hashes[token.Hash] = name
+	return value
 }
 
 func generateToken(length int) string {
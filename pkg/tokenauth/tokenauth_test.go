@@ -3,6 +3,8 @@ package tokenauth_test
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -46,7 +48,7 @@ func Test_TokenAuth_002(t *testing.T) {
 
 	// Write out events
 	go func() {
-		for evt := range auth.C() {
+		for evt := range auth.Sub() {
 			t.Log(evt)
 		}
 	}()
@@ -55,9 +57,11 @@ func Test_TokenAuth_002(t *testing.T) {
 	go func() {
 		for i := 0; i < 3; i++ {
 			time.Sleep(time.Second)
-			t.Log("Revoke admin token")
-			if err := auth.(plugin.TokenAuth).Revoke(AdminToken); err != nil {
+			t.Log("Rotate admin token")
+			if value, err := auth.(plugin.TokenAuth).Rotate(); err != nil {
 				t.Error(err)
+			} else if name := auth.(plugin.TokenAuth).Matches(value); name != AdminToken {
+				t.Errorf("Unexpected match: %q", name)
 			}
 		}
 	}()
@@ -86,14 +90,14 @@ func Test_TokenAuth_003(t *testing.T) {
 
 	// Write out events
 	go func() {
-		for evt := range auth.C() {
+		for evt := range auth.Sub() {
 			t.Log(evt)
 		}
 	}()
 
 	// Create and then revoke a token
 	go func() {
-		value, err := auth.(plugin.TokenAuth).Create("test")
+		value, err := auth.(plugin.TokenAuth).Create("test", time.Time{})
 		if err != nil {
 			t.Error(err)
 		} else {
@@ -124,3 +128,223 @@ func Test_TokenAuth_003(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func Test_TokenAuth_004(t *testing.T) {
+	// Write a tokens file with plaintext values
+	path := t.TempDir()
+	filename := filepath.Join(path, DefaultLabel+".json")
+	if err := os.WriteFile(filename, []byte(`{"admin":{"token":"secret"},"user":{"token":"user-secret"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tokens should be migrated
+	auth, err := Config{Path: path}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(data), "secret") {
+		t.Error("Expected plaintext values to be removed:", string(data))
+	}
+	if info, err := os.Stat(filename); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Error("Unexpected file mode:", info.Mode())
+	}
+
+	// Values should still match
+	if name := auth.(plugin.TokenAuth).Matches("secret"); name != AdminToken {
+		t.Errorf("Unexpected match: %q", name)
+	}
+	if name := auth.(plugin.TokenAuth).Matches("user-secret"); name != "user" {
+		t.Errorf("Unexpected match: %q", name)
+	}
+	if name := auth.(plugin.TokenAuth).Matches("other"); name != "" {
+		t.Errorf("Unexpected match: %q", name)
+	}
+
+	// Values should match after reading the migrated file
+	auth, err = Config{Path: path}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if name := auth.(plugin.TokenAuth).Matches("user-secret"); name != "user" {
+		t.Errorf("Unexpected match: %q", name)
+	}
+}
+
+func Test_TokenAuth_005(t *testing.T) {
+	path := t.TempDir()
+	auth, err := Config{Path: path, Delta: time.Second}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a token, which should not be stored on disk
+	value, err := auth.(plugin.TokenAuth).Create("test", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	} else if name := auth.(plugin.TokenAuth).Matches(value); name != "test" {
+		t.Errorf("Unexpected match: %q", name)
+	}
+
+	// Run, and receive the event for the admin token, which should not
+	// contain the value
+	ch := auth.Sub()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	go auth.Run(ctx)
+
+	for evt := range ch {
+		if evt.Key() == plugin.TokenRotated {
+			if evt.Value() != AdminToken {
+				t.Errorf("Unexpected value: %q", evt.Value())
+			}
+			break
+		}
+	}
+
+	// Read the admin token value from the admin file, which is only
+	// readable by the owner
+	filename := filepath.Join(path, DefaultLabel+".admin")
+	var admin string
+	if info, err := os.Stat(filename); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Error("Unexpected file mode:", info.Mode())
+	} else if data, err := os.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	} else {
+		admin = strings.TrimSpace(string(data))
+	}
+	if name := auth.(plugin.TokenAuth).Matches(admin); name != AdminToken {
+		t.Errorf("Unexpected match: %q", name)
+	}
+
+	// Neither value should be written to disk
+	<-ctx.Done()
+	if data, err := os.ReadFile(filepath.Join(path, DefaultLabel+".json")); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(data), value) || strings.Contains(string(data), admin) {
+		t.Error("Expected token values not to be stored:", string(data))
+	}
+}
+
+func Test_TokenAuth_006(t *testing.T) {
+	// Invalid scopes
+	for _, v := range []string{"", "nginx", "nginx:", "nginx:READ", "nginx:GET,FOO", "00:read"} {
+		if _, err := ParseScope(v); err == nil {
+			t.Errorf("Expected error for %q", v)
+		}
+	}
+
+	tests := []struct {
+		Scope, Prefix, Method string
+		Allowed, Admin        bool
+	}{
+		{"nginx:read", "/nginx/v1/", "GET", true, false},
+		{"nginx:read", "/nginx/v1/", "POST", false, false},
+		{"nginx:read", "/tokenauth/v1/", "GET", false, false},
+		{"nginx:write", "/nginx/v1/", "DELETE", true, false},
+		{"tokenauth:admin", "/tokenauth/v1/", "POST", true, true},
+		{"tokenauth:admin", "/nginx/v1/", "GET", false, false},
+		{"/nginx/v1:GET,PATCH", "/nginx/v1/", "PATCH", true, false},
+		{"/nginx/v1:GET,PATCH", "/nginx/v1/", "DELETE", false, false},
+		{"/nginx:GET", "/nginx/v1/", "GET", true, false},
+		{"/nginx/v2:GET", "/nginx/v1/", "GET", false, false},
+	}
+	for i, test := range tests {
+		scope, err := ParseScope(test.Scope)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if allowed, admin := scope.Allows(test.Prefix, test.Method); allowed != test.Allowed || admin != test.Admin {
+			t.Errorf("Test %d: %v: unexpected result %v, %v", i, scope, allowed, admin)
+		}
+	}
+}
+
+func Test_TokenAuth_007(t *testing.T) {
+	path := t.TempDir()
+	auth, err := Config{Path: path, Delta: 100 * time.Millisecond, Idle: time.Second}.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expiry in the past should fail
+	if _, err := auth.(plugin.TokenAuth).Create("past", time.Now().Add(-time.Second)); err == nil {
+		t.Error("Expected error for expiry in the past")
+	}
+
+	// Create a token which expires, and one which expires when idle
+	expires := time.Now().Add(500 * time.Millisecond)
+	value, err := auth.(plugin.TokenAuth).Create("expires", expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.(plugin.TokenAuth).Create("idle", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Enumerate should report expiry, and the admin token should not expire
+	tokens := auth.(plugin.TokenAuth).Enumerate()
+	if !tokens["expires"].Expires().Equal(expires) {
+		t.Error("Unexpected expiry:", tokens["expires"].Expires())
+	}
+	if tokens["idle"].Expires().IsZero() {
+		t.Error("Expected idle timeout")
+	}
+	if !tokens[AdminToken].Expires().IsZero() {
+		t.Error("Expected admin token not to expire")
+	}
+
+	// Run, and collect expired tokens
+	ch := auth.Sub()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go auth.Run(ctx)
+
+	expired := map[string]bool{}
+	for evt := range ch {
+		if evt.Key() == plugin.TokenExpired {
+			expired[evt.Value().(string)] = true
+		}
+	}
+	if !expired["expires"] || !expired["idle"] || expired[AdminToken] {
+		t.Error("Unexpected expired tokens:", expired)
+	}
+	if auth.(plugin.TokenAuth).Matches(value) != "" {
+		t.Error("Expected expired token not to match")
+	}
+	if auth.(plugin.TokenAuth).Exists("idle") {
+		t.Error("Expected idle token to be removed")
+	}
+}
+
+func Test_TokenAuth_008(t *testing.T) {
+	tests := []struct {
+		Scopes, Other []string
+		Covers        bool
+	}{
+		{[]string{"tokenauth:admin"}, []string{"tokenauth:read"}, true},
+		{[]string{"tokenauth:admin"}, []string{"tokenauth:admin"}, true},
+		{[]string{"tokenauth:admin"}, nil, false},
+		{[]string{"tokenauth:admin"}, []string{"nginx:read"}, false},
+		{[]string{"tokenauth:admin"}, []string{"/:admin"}, false},
+		{[]string{"tokenauth:admin"}, []string{"/tokenauth/v1:GET"}, true},
+		{[]string{"nginx:write"}, []string{"nginx:admin"}, false},
+		{[]string{"nginx:write"}, []string{"/nginx/v1:GET,PATCH"}, true},
+		{[]string{"/nginx/v1:GET,PATCH"}, []string{"nginx:read"}, false},
+		{[]string{"/nginx/v1:GET,PATCH"}, []string{"/nginx/v1:PATCH"}, true},
+		{[]string{"/nginx/v1:GET,PATCH"}, []string{"/nginx/v1:DELETE"}, false},
+		{[]string{"nginx:read", "tokenauth:read"}, []string{"tokenauth:GET", "nginx:HEAD"}, true},
+		{[]string{"/tokenauth:admin"}, []string{"tokenauth:write", "/tokenauth/v1:admin"}, true},
+		{[]string{"/tokenauth:admin"}, []string{"tokenauth:invalid"}, false},
+	}
+	for i, test := range tests {
+		if covers := Covers(test.Scopes, test.Other); covers != test.Covers {
+			t.Errorf("Test %d: %v covers %v: unexpected result %v", i, test.Scopes, test.Other, covers)
+		}
+	}
+}
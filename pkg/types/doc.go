@@ -1,2 +1,3 @@
-// types package implements duration time
+// types package implements duration, task reference and supervision
+// policy types for task configurations
 package types
@@ -0,0 +1,13 @@
+package types
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Policy is the supervision policy for a task, which determines whether
+// the task is restarted when it exits. Attributes which are not set use
+// the default policy of the provider
+type Policy struct {
+	Restart     string   `hcl:"restart,optional" json:"restart,omitempty"`           // One of "never", "on-failure" or "always"
+	MaxRestarts uint     `hcl:"max_restarts,optional" json:"max_restarts,omitempty"` // Maximum number of restarts
+	Backoff     Duration `hcl:"backoff,optional" json:"backoff,omitempty"`           // Delay before the first restart
+}
@@ -4,6 +4,7 @@ import (
 	// Modules
 
 	"strconv"
+	"strings"
 
 	iface "github.com/mutablelogic/terraform-provider-nginx"
 )
@@ -19,6 +20,18 @@ type Task struct {
 /////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
+// Label returns the label of the task, or the label in the reference
+// if the task does not have a label
+func (t Task) Label() string {
+	if task, ok := t.Task.(interface{ Label() string }); ok {
+		return task.Label()
+	} else if i := strings.LastIndex(t.Ref, "."); i >= 0 {
+		return t.Ref[i+1:]
+	} else {
+		return ""
+	}
+}
+
 func (t *Task) UnmarshalJSON(data []byte) error {
 	if v, err := strconv.Unquote(string(data)); err != nil {
 		return err
@@ -18,10 +18,11 @@ type ErrorResponse struct {
 // GLOBALS
 
 const (
-	ContentTypeKey   = "Content-Type"
-	ContentLengthKey = "Content-Length"
-	ContentTypeJSON  = "application/json"
-	ContentTypeText  = "text/plain"
+	ContentTypeKey         = "Content-Type"
+	ContentLengthKey       = "Content-Length"
+	ContentTypeJSON        = "application/json"
+	ContentTypeText        = "text/plain"
+	ContentTypeEventStream = "text/event-stream"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
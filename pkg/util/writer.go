@@ -0,0 +1,79 @@
+package util
+
+import (
+	"net/http"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ResponseWriter records the status code and number of bytes written
+// in a response, and the name of the token which authenticated the request
+type ResponseWriter struct {
+	http.ResponseWriter
+	Status int
+	Size   int
+	Name   string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewResponseWriter returns a ResponseWriter which wraps a http.ResponseWriter
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// SetName records the name of the token which authenticated a request on
+// each ResponseWriter which wraps the response, so that middleware which
+// wraps the response can retrieve the name once the request is served
+func SetName(w http.ResponseWriter, name string) {
+	for w != nil {
+		if rw, ok := w.(*ResponseWriter); ok {
+			rw.Name = name
+		}
+		if unwrap, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+			w = unwrap.Unwrap()
+		} else {
+			break
+		}
+	}
+}
+
+// WriteHeader records the status code and writes the header
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.Status == 0 {
+		w.Status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written, and sets the status code
+// if the header has not been written
+func (w *ResponseWriter) Write(data []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.Size += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if supported by the
+// underlying http.ResponseWriter
+func (w *ResponseWriter) Flush() {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	// Modules
+	accesslog "github.com/mutablelogic/terraform-provider-nginx/pkg/accesslog"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+)
+
+func Config() TaskPlugin {
+	return accesslog.Config{}
+}
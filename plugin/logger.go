@@ -7,13 +7,56 @@ import (
 	. "github.com/mutablelogic/terraform-provider-nginx"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The level of a log message
+type LogLevel int
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
 // Logger provides logging services
 type Logger interface {
 	Task
 
-	// Log a message with arguments
+	// Log a message with arguments at info level
 	Log(context.Context, ...any)
 
-	// Log a message with formatted arguments
+	// Log a message with formatted arguments at info level
 	Logf(context.Context, string, ...any)
+
+	// Log a message with arguments at a level
+	Print(context.Context, LogLevel, ...any)
+
+	// Log a message with formatted arguments at a level
+	Printf(context.Context, LogLevel, string, ...any)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	LogDebug LogLevel = -4 // Debugging messages
+	LogInfo  LogLevel = 0  // Informational messages
+	LogWarn  LogLevel = 4  // Warnings
+	LogError LogLevel = 8  // Errors
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (v LogLevel) String() string {
+	switch v {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	default:
+		return "[?? Invalid LogLevel value]"
+	}
 }
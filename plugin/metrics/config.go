@@ -0,0 +1,13 @@
+package main
+
+import (
+	// Modules
+	metrics "github.com/mutablelogic/terraform-provider-nginx/pkg/metrics"
+
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+)
+
+func Config() TaskPlugin {
+	return metrics.Config{}
+}
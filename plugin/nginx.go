@@ -1,10 +1,23 @@
 package plugin
 
 import (
+	"context"
+
 	// Namespace imports
 	. "github.com/mutablelogic/terraform-provider-nginx"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The nginx event type. The value for each configuration event is the
+// name of the configuration. When an operation fails, an error is emitted
+// with the event type as the key
+type NginxEventType uint
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
 // Nginx provides management of configurations
 type Nginx interface {
 	Task
@@ -23,6 +36,14 @@ type Nginx interface {
 
 	// Disable a configuration
 	Disable(NginxConfig) error
+
+	// Test the configuration, and return any diagnostics. Returns
+	// an error if the configuration test failed
+	Test(context.Context) ([]NginxDiagnostic, error)
+
+	// Reload the configuration, and return when the reload has
+	// been confirmed or the context is done
+	Reload(context.Context) error
 }
 
 // NginxConfig provides a configuration that can be enabled or revoked
@@ -32,4 +53,50 @@ type NginxConfig interface {
 
 	// Return the state of the configuration
 	Enabled() bool
+
+	// Return the body of the configuration
+	Read() ([]byte, error)
+}
+
+// NginxDiagnostic is a warning or error returned when testing configuration
+type NginxDiagnostic interface {
+	// Return the file which caused the diagnostic, or empty string
+	File() string
+
+	// Return the line number within the file, or zero
+	Line() int
+
+	// Return the diagnostic message
+	Message() string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	NginxReload  NginxEventType = iota // Configuration was reloaded
+	NginxCreate                        // A configuration was created
+	NginxRevoke                        // A configuration was revoked
+	NginxEnable                        // A configuration was enabled
+	NginxDisable                       // A configuration was disabled
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (v NginxEventType) String() string {
+	switch v {
+	case NginxReload:
+		return "NginxReload"
+	case NginxCreate:
+		return "NginxCreate"
+	case NginxRevoke:
+		return "NginxRevoke"
+	case NginxEnable:
+		return "NginxEnable"
+	case NginxDisable:
+		return "NginxDisable"
+	default:
+		return "[?? Invalid NginxEventType value]"
+	}
 }
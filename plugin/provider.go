@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	// Namespace imports
+	. "github.com/mutablelogic/terraform-provider-nginx"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The provider event type. The value for each task event is the task key,
+// which is the name and label of the task separated by a period
+type ProviderEventType uint
+
+// The state of a task within a provider
+type TaskState uint
+
+// ProviderTask describes a task within a provider
+type ProviderTask struct {
+	Key    string    // The name and label of the task separated by a period
+	Task   Task      // The task
+	State  TaskState // The state of the task
+	Err    error     // The error returned when the task last failed
+	Events []Event   // Recent events emitted by the task, oldest first
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// TaskProvider is a provider which can describe its tasks
+type TaskProvider interface {
+	Provider
+
+	// Return the tasks in the order they were created
+	Tasks() []ProviderTask
+
+	// Return a task by key, or nil if the task does not exist
+	Get(string) *ProviderTask
+
+	// Return a channel on which events from all tasks are received, where
+	// the key of each event is the task key and the value is the event
+	SubTasks() <-chan Event
+
+	// Unsubscribe a channel returned by SubTasks
+	UnsubTasks(<-chan Event)
+
+	// Call a function for every event from tasks, with the task key and the
+	// event, until the returned function is called. No events are dropped,
+	// so the function should return quickly
+	ObserveTasks(func(string, Event)) func()
+}
+
+// Sensitive is implemented by event keys for events whose values should
+// not be disclosed, so the values are redacted by a provider
+type Sensitive interface {
+	Sensitive() bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ProviderTaskExit    ProviderEventType = iota // A task exited
+	ProviderTaskTimeout                          // A task did not exit within the grace period
+	ProviderTaskRestart                          // A task exited and is being restarted
+	ProviderTaskStart                            // A task was created and started whilst running
+	ProviderTaskRemoved                          // A task was removed
+	ProviderReload                               // Configurations were reloaded, where the value describes the changes
+)
+
+const (
+	TaskCreated    TaskState = iota // The task has not been started
+	TaskRunning                     // The task is running
+	TaskRestarting                  // The task exited and is waiting to be restarted
+	TaskFailed                      // The task exited with an error
+	TaskStopped                     // The task exited without an error
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (v ProviderEventType) String() string {
+	switch v {
+	case ProviderTaskExit:
+		return "ProviderTaskExit"
+	case ProviderTaskTimeout:
+		return "ProviderTaskTimeout"
+	case ProviderTaskRestart:
+		return "ProviderTaskRestart"
+	case ProviderTaskStart:
+		return "ProviderTaskStart"
+	case ProviderTaskRemoved:
+		return "ProviderTaskRemoved"
+	case ProviderReload:
+		return "ProviderReload"
+	default:
+		return "[?? Invalid ProviderEventType value]"
+	}
+}
+
+func (v TaskState) String() string {
+	switch v {
+	case TaskCreated:
+		return "created"
+	case TaskRunning:
+		return "running"
+	case TaskRestarting:
+		return "restarting"
+	case TaskFailed:
+		return "failed"
+	case TaskStopped:
+		return "stopped"
+	default:
+		return "[?? Invalid TaskState value]"
+	}
+}
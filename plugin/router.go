@@ -3,11 +3,31 @@ package plugin
 import (
 	"net/http"
 	"regexp"
+	"time"
 
 	// Namespace imports
 	. "github.com/mutablelogic/terraform-provider-nginx"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The router event type
+type RouterEventType uint
+
+// RequestInfo describes a request served by a router. The prefix and path
+// are empty when no route matched the request
+type RequestInfo struct {
+	Prefix   string        // The prefix of the gateway for the route
+	Path     string        // The path pattern for the route
+	Method   string        // The request method
+	Status   int           // The response status code
+	Duration time.Duration // The time taken to serve the request
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
 // Router is a task which maps paths to routes
 type Router interface {
 	Task
@@ -18,4 +38,29 @@ type Router interface {
 
 	// Add middleware handler to the router given unique name
 	AddMiddleware(string, func(http.HandlerFunc) http.HandlerFunc) error
+
+	// Remove all prefix/path mappings added by a gateway
+	RemoveHandler(Gateway) error
+
+	// Remove a middleware handler by name
+	RemoveMiddleware(string) error
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	RouterRequest RouterEventType = iota // A request was served, where the value is the RequestInfo
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (v RouterEventType) String() string {
+	switch v {
+	case RouterRequest:
+		return "RouterRequest"
+	default:
+		return "[?? Invalid RouterEventType value]"
+	}
 }
@@ -7,6 +7,12 @@ import (
 	. "github.com/mutablelogic/terraform-provider-nginx"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// The token event type
+type TokenAuthEventType uint
+
 // TokenAuth stores tokens for authentication
 type TokenAuth interface {
 	Task
@@ -14,18 +20,72 @@ type TokenAuth interface {
 	// Return true if a token associated with the name already exists
 	Exists(string) bool
 
-	// Create a new token associated with a name and return it.
-	Create(string) (string, error)
+	// Create a new token associated with a name, an optional expiry time
+	// and optional scopes, and return it.
+	Create(string, time.Time, ...string) (string, error)
 
 	// Revoke a token associated with a name. For the admin token, it is
 	// rotated rather than revoked.
 	Revoke(string) error
 
-	// Return all token names and their last access times
-	Enumerate() map[string]time.Time
+	// Rotate the admin token, and return the new value. The value is never
+	// emitted as an event
+	Rotate() (string, error)
+
+	// Return all token names and information about each token
+	Enumerate() map[string]TokenInfo
+
+	// Return the scopes for a token, or nil if the token has no scopes
+	Scopes(string) []string
 
 	// Returns the name of the token if a value matches. Updates
 	// the access time for the token. If token with value not
 	// found, then return empty string
 	Matches(string) string
 }
+
+// TokenInfo describes a token, without the token value
+type TokenInfo interface {
+	// Return the scopes for the token
+	Scopes() []string
+
+	// Return the last access time for the token
+	AccessTime() time.Time
+
+	// Return the time the token expires, either because of an expiry
+	// time or idle timeout, or zero if the token does not expire
+	Expires() time.Time
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	TokenExpired TokenAuthEventType = iota // A token expired and was removed
+	TokenMatch                             // A token value matched, where the value is the token name
+	TokenMiss                              // A token value did not match
+	TokenRotated                           // The admin token was created or rotated, where the value is the token name
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// Sensitive returns true, as token events are redacted by a provider
+func (v TokenAuthEventType) Sensitive() bool {
+	return true
+}
+
+func (v TokenAuthEventType) String() string {
+	switch v {
+	case TokenExpired:
+		return "TokenExpired"
+	case TokenMatch:
+		return "TokenMatch"
+	case TokenMiss:
+		return "TokenMiss"
+	case TokenRotated:
+		return "TokenRotated"
+	default:
+		return "[?? Invalid TokenAuthEventType value]"
+	}
+}